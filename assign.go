@@ -0,0 +1,169 @@
+package jsonextract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// maxAssignmentLength is the number of bytes before a value that are searched for the variable or property it is assigned to
+const maxAssignmentLength = 256
+
+// globalObjects are the names of the global object in browsers, properties of them are also global variables
+var globalObjects = []string{"window.", "self.", "globalThis."}
+
+// ErrNotAssigned is returned by ObjectByName if no object or array is assigned to the name
+var ErrNotAssigned = errors.New("no object is assigned to this name")
+
+// ObjectByName returns the first object or array in r that is assigned to the variable or property name,
+// e.g. "ytInitialData" for var ytInitialData = {...}. Properties of the global object can be found
+// with or without it, e.g. "__APOLLO_STATE__" also finds window["__APOLLO_STATE__"] = {...}.
+//
+// Names use dots for properties, brackets are only used for keys that are not valid identifiers, e.g. a["b-c"][0].
+// If no such object is found, ErrNotAssigned is returned.
+func ObjectByName(r io.Reader, name string) (m Match, err error) {
+	name = trimGlobalObject(name)
+
+	var found bool
+	err = ReaderWithOptions(context.Background(), r, ExtractOptions{ReportAssignments: true}, func(match Match) error {
+		if match.AssignedTo == "" || trimGlobalObject(match.AssignedTo) != name {
+			return nil
+		}
+
+		m, found = match, true
+		return ErrStop
+	})
+	if err == nil && !found {
+		err = ErrNotAssigned
+	}
+
+	return
+}
+
+// trimGlobalObject removes the global object from the start of name, e.g. window.a becomes a
+func trimGlobalObject(name string) string {
+	for _, prefix := range globalObjects {
+		if strings.HasPrefix(name, prefix) {
+			return name[len(prefix):]
+		}
+	}
+	return name
+}
+
+// assignmentTarget returns the variable or property that the value following b is assigned to, e.g. "x" for "var x = ".
+// Brackets with a key are converted to dots where possible, e.g. window["a"] becomes window.a.
+// inputStart must be set if b starts at the beginning of the input, otherwise an expression that starts
+// at the beginning of b might have been cut off. If b doesn't end with an assignment, an empty string is returned
+func assignmentTarget(b []byte, inputStart bool) string {
+	var i = skipJSSpaceBackward(b, len(b))
+	if i < 2 || b[i-1] != '=' || strings.IndexByte("=!<>+-*/%&|^?", b[i-2]) >= 0 {
+		return ""
+	}
+	i = skipJSSpaceBackward(b, i-1)
+
+	// parts contains the parts of the member expression in reverse order, e.g. [0], .b and .a for a.b[0]
+	var parts []string
+	for {
+		if i > 0 && b[i-1] == ']' {
+			open := bytes.LastIndexByte(b[:i-1], '[')
+			if open < 0 {
+				return ""
+			}
+
+			part, ok := bracketAccessor(bytes.TrimSpace(b[open+1 : i-1]))
+			if !ok {
+				return ""
+			}
+
+			parts = append(parts, part)
+			i = open
+			continue
+		}
+
+		var j = i
+		for j > 0 && isIdentifierByte(b[j-1]) {
+			j--
+		}
+		if j == i || '0' <= b[j] && b[j] <= '9' || j == 0 && !inputStart {
+			return ""
+		}
+
+		parts = append(parts, "."+string(b[j:i]))
+		i = j
+
+		if i == 0 || b[i-1] != '.' {
+			break
+		}
+		i--
+	}
+
+	var name strings.Builder
+	for k := len(parts) - 1; k >= 0; k-- {
+		name.WriteString(parts[k])
+	}
+
+	return strings.TrimPrefix(name.String(), ".")
+}
+
+// bracketAccessor returns how the key in a property access like a["key"] or a[0] is written in the name of a member expression
+func bracketAccessor(key []byte) (string, bool) {
+	if len(key) == 0 {
+		return "", false
+	}
+
+	if key[0] != '"' && key[0] != '\'' {
+		for _, c := range key {
+			if c < '0' || c > '9' {
+				return "", false
+			}
+		}
+		return "[" + string(key) + "]", true
+	}
+
+	if len(key) < 2 || key[len(key)-1] != key[0] {
+		return "", false
+	}
+	s, err := unquoteJSString(key)
+	if err != nil {
+		return "", false
+	}
+
+	if isIdentifierName(s) {
+		return "." + string(s), true
+	}
+
+	quoted, err := json.Marshal(string(s))
+	if err != nil {
+		return "", false
+	}
+	return "[" + string(quoted) + "]", true
+}
+
+// isIdentifierName returns whether s can be used as property name after a dot. Only ASCII identifiers are recognized
+func isIdentifierName(s []byte) bool {
+	if len(s) == 0 || '0' <= s[0] && s[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		if !isIdentifierByte(c) || c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifierByte returns whether c can be part of an identifier. All non-ASCII bytes are accepted
+func isIdentifierByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
+
+// skipJSSpaceBackward returns the index after the last byte before i in b that is not whitespace
+func skipJSSpaceBackward(b []byte, i int) int {
+	for i > 0 && (b[i-1] == ' ' || b[i-1] == '\t' || b[i-1] == '\n' || b[i-1] == '\r') {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,135 @@
+package jsonextract
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_assignmentTarget(t *testing.T) {
+	tests := []struct {
+		before string
+		want   string
+	}{
+		{"var ytInitialData = ", "ytInitialData"},
+		{"let a=", "a"},
+		{"const $x_1 =\n\t", "$x_1"},
+		{"x = y = ", "y"},
+		{"window.a.b = ", "window.a.b"},
+		{`window["__APOLLO_STATE__"] = `, "window.__APOLLO_STATE__"},
+		{`self['a']["b-c"][0] = `, `self.a["b-c"][0]`},
+		{`a[ "b" ].c = `, "a.b.c"},
+		{"if (a == ", ""},
+		{"a += ", ""},
+		{"a => ", ""},
+		{"x !== ", ""},
+		{"const {a} = ", ""},
+		{"f() = ", ""},
+		{"a[b] = ", ""},
+		{"a. = ", ""},
+		{"1a = ", ""},
+		{"= ", ""},
+		{"var x = 5, y: ", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.before, func(t *testing.T) {
+			if got := assignmentTarget([]byte(tt.before), true); got != tt.want {
+				t.Errorf("assignmentTarget(%q) = %q, want %q", tt.before, got, tt.want)
+			}
+		})
+	}
+
+	// The start of the name might have been cut off
+	if got := assignmentTarget([]byte("Data = "), false); got != "" {
+		t.Errorf("assignmentTarget with possibly truncated name = %q, want an empty string", got)
+	}
+}
+
+func TestReaderReportAssignments(t *testing.T) {
+	var input = strings.Repeat(" ", 3*maxAssignmentLength) + `
+		var ytInitialData = {"a": {"b": 1}};
+		window["__APOLLO_STATE__"] = [1, 2];
+		f({"c": 2});
+		var parsed = JSON.parse("{\"d\": 3}");
+		var many = JSON.parse("[4] [5]");
+	`
+
+	type result struct {
+		data, assignedTo string
+	}
+	var want = []result{
+		{`{"a":{"b":1}}`, "ytInitialData"},
+		// The brackets of the property access are a valid array
+		{`["__APOLLO_STATE__"]`, ""},
+		{`[1,2]`, "window.__APOLLO_STATE__"},
+		{`{"c":2}`, ""},
+		{`{"d":3}`, "parsed"},
+		{`[4]`, ""},
+		{`[5]`, ""},
+	}
+
+	for _, reader := range []func(io.Reader) io.Reader{
+		func(r io.Reader) io.Reader { return r },
+		iotest.OneByteReader,
+	} {
+		var got []result
+		err := ReaderWithOptions(context.Background(), reader(strings.NewReader(input)), ExtractOptions{ReportAssignments: true, UnwrapJSONParse: true}, func(m Match) error {
+			got = append(got, result{string(m.Data), m.AssignedTo})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	// Nested values are not assigned to anything
+	err := ReaderWithOptions(context.Background(), strings.NewReader(`x = {"a": {"b": 1}}`), ExtractOptions{ReportAssignments: true}, func(m Match) error {
+		if s := m.sub(0, m.Data); s.AssignedTo != "x" {
+			t.Errorf("sub match for the whole value has AssignedTo %q, want %q", s.AssignedTo, "x")
+		}
+		if s := m.sub(5, m.Data[5:12]); s.AssignedTo != "" {
+			t.Errorf("sub match for a nested value has AssignedTo %q, want an empty string", s.AssignedTo)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestObjectByName(t *testing.T) {
+	var input = `
+		<script>var other = {"a": 1};</script>
+		<script>window["ytInitialData"] = {"b": 2}; var ytInitialData = {"c": 3};</script>
+	`
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{"other", `{"a":1}`, nil},
+		{"ytInitialData", `{"b":2}`, nil},
+		{"window.ytInitialData", `{"b":2}`, nil},
+		{"missing", "", ErrNotAssigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ObjectByName(strings.NewReader(input), tt.name)
+			if err != tt.wantErr {
+				t.Fatalf("ObjectByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(m.Data) != tt.want {
+				t.Errorf("ObjectByName() = %s, want %s", string(m.Data), tt.want)
+			}
+		})
+	}
+}
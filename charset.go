@@ -0,0 +1,173 @@
+package jsonextract
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// charsetPrescanLength is the number of bytes at the start of the input that are searched for a byte order mark or <meta charset> tag
+const charsetPrescanLength = 1024
+
+// DecodeReader returns a reader that converts the input of r to UTF-8, which is what all other functions expect.
+// It also returns the name of the encoding it detected, e.g. "shift_jis" or "utf-16le".
+//
+// The encoding is detected from the first of these that is found:
+//   - a byte order mark at the start of the input
+//   - the charset parameter of contentType, which should be the Content-Type header of an HTTP response. It can be empty
+//   - a <meta charset="..."> or <meta http-equiv="Content-Type" content="...; charset=..."> tag near the start of the input
+//   - zero bytes in the first two bytes, which means that the input is UTF-16 text like a JSON file without byte order mark
+//
+// If none of them is found, the input is assumed to be UTF-8 if its start is valid UTF-8 and windows-1252 otherwise, similar to what browsers do.
+// The byte order mark is not part of the returned input.
+func DecodeReader(r io.Reader, contentType string) (reader io.Reader, name string, err error) {
+	br := bufio.NewReaderSize(r, charsetPrescanLength)
+
+	head, err := br.Peek(charsetPrescanLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+
+	enc, bomLength := detectEncoding(head, contentType)
+	if bomLength > 0 {
+		if _, err = br.Discard(bomLength); err != nil {
+			return nil, "", err
+		}
+	}
+
+	name, err = htmlindex.Name(enc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if name == "utf-8" {
+		return br, name, nil
+	}
+
+	return transform.NewReader(br, enc.NewDecoder()), name, nil
+}
+
+// detectEncoding returns the encoding of the input that starts with head, see DecodeReader.
+// bomLength is the length of the byte order mark at the start of head, which should be removed
+func detectEncoding(head []byte, contentType string) (enc encoding.Encoding, bomLength int) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8, 3
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), 2
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), 2
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc, err := htmlindex.Get(params["charset"]); err == nil {
+			return enc, 0
+		}
+	}
+
+	if enc := metaCharset(head); enc != nil {
+		return enc, 0
+	}
+
+	if len(head) >= 2 {
+		switch {
+		case head[0] == 0 && head[1] != 0:
+			return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), 0
+		case head[0] != 0 && head[1] == 0:
+			return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), 0
+		}
+	}
+
+	if validUTF8Prefix(head, len(head) == charsetPrescanLength) {
+		return unicode.UTF8, 0
+	}
+	return charmap.Windows1252, 0
+}
+
+// metaCharset returns the encoding declared by a <meta> tag in head, or nil if there is none
+func metaCharset(head []byte) encoding.Encoding {
+	input := parse.NewInputBytes(head)
+	defer input.Restore()
+
+	var (
+		lex = html.NewLexer(input)
+
+		inMeta              bool
+		charset, httpEquiv  string
+		contentTypeFromMeta string
+	)
+
+	for {
+		tt, _ := lex.Next()
+		switch tt {
+		case html.ErrorToken:
+			return nil
+		case html.StartTagToken:
+			inMeta = string(lex.Text()) == "meta"
+			charset, httpEquiv, contentTypeFromMeta = "", "", ""
+		case html.AttributeToken:
+			if !inMeta {
+				continue
+			}
+
+			switch strings.ToLower(string(lex.AttrKey())) {
+			case "charset":
+				charset = attributeValue(lex.AttrVal())
+			case "http-equiv":
+				httpEquiv = attributeValue(lex.AttrVal())
+			case "content":
+				contentTypeFromMeta = attributeValue(lex.AttrVal())
+			}
+		case html.StartTagCloseToken, html.StartTagVoidToken:
+			if !inMeta {
+				continue
+			}
+			inMeta = false
+
+			if charset == "" && strings.EqualFold(httpEquiv, "content-type") {
+				if _, params, err := mime.ParseMediaType(contentTypeFromMeta); err == nil {
+					charset = params["charset"]
+				}
+			}
+
+			enc, err := htmlindex.Get(strings.TrimSpace(charset))
+			if err != nil {
+				continue
+			}
+
+			// The tag could only be read because the input is compatible with ASCII, so it can't be UTF-16
+			if name, _ := htmlindex.Name(enc); strings.HasPrefix(name, "utf-16") {
+				return unicode.UTF8
+			}
+			return enc
+		}
+	}
+}
+
+// validUTF8Prefix returns whether head is valid UTF-8. If head was truncated, a rune at the end that might have been cut off is ignored
+func validUTF8Prefix(head []byte, truncated bool) bool {
+	if utf8.Valid(head) {
+		return true
+	}
+	if !truncated {
+		return false
+	}
+
+	for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			return !utf8.FullRune(head[i:]) && utf8.Valid(head[:i])
+		}
+	}
+	return false
+}
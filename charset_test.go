@@ -0,0 +1,102 @@
+package jsonextract
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) string {
+	t.Helper()
+
+	out, err := enc.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("encoding %q: %v", s, err)
+	}
+	return out
+}
+
+func TestDecodeReader(t *testing.T) {
+	const text = `{"title": "日本語のタイトル"}`
+
+	tests := []struct {
+		name        string
+		input       string
+		contentType string
+
+		want     string
+		wantName string
+	}{
+		{"utf-8", text, "", text, "utf-8"},
+		{"utf-8 with bom", "\xEF\xBB\xBF" + text, "", text, "utf-8"},
+		{"utf-16le with bom", "\xFF\xFE" + encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), text), "", text, "utf-16le"},
+		{"utf-16be with bom", "\xFE\xFF" + encode(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), text), "", text, "utf-16be"},
+		{"utf-16le without bom", encode(t, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), text), "", text, "utf-16le"},
+		{"utf-16be without bom", encode(t, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), text), "", text, "utf-16be"},
+		{"content type", encode(t, japanese.ShiftJIS, text), "text/html; charset=Shift_JIS", text, "shift_jis"},
+		{
+			"meta charset",
+			`<html><head><meta charset="gbk"><script>var x = ` + encode(t, simplifiedchinese.GBK, `{"t": "中文"}`) + `</script>`,
+			"",
+			`<html><head><meta charset="gbk"><script>var x = {"t": "中文"}</script>`,
+			"gbk",
+		},
+		{
+			"meta http-equiv",
+			`<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><p>caf` + "\xE9</p>",
+			"",
+			`<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><p>café</p>`,
+			"windows-1252",
+		},
+		{"content type before meta", `<meta charset="gbk">` + "\xE9", "text/html; charset=iso-8859-1", `<meta charset="gbk">é`, "windows-1252"},
+		{"meta utf-16", `<meta charset="utf-16"><p>ü</p>`, "", `<meta charset="utf-16"><p>ü</p>`, "utf-8"},
+		{"invalid utf-8", "caf\xE9", "", "café", "windows-1252"},
+		{"empty", "", "", "", "utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, name, err := DecodeReader(strings.NewReader(tt.input), tt.contentType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if name != tt.wantName {
+				t.Errorf("DecodeReader() detected %q, want %q", name, tt.wantName)
+			}
+
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("unexpected error while reading: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DecodeReader() returned %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeReaderLongInput(t *testing.T) {
+	// The last rune within the first bytes is cut off, which must not be mistaken for invalid UTF-8
+	var input = strings.Repeat("a", charsetPrescanLength-1) + "ü"
+
+	r, name, err := DecodeReader(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "utf-8" {
+		t.Errorf("DecodeReader() detected %q, want %q", name, "utf-8")
+	}
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unexpected error while reading: %v", err)
+	}
+	if !bytes.Equal(got, []byte(input)) {
+		t.Errorf("DecodeReader() changed the input")
+	}
+}
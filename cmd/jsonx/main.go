@@ -14,10 +14,12 @@ import (
 	"time"
 
 	"github.com/xarantolus/jsonextract"
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 var (
-	limit = flag.Int("limit", -1, "Stop extracting after this many objects")
+	limit   = flag.Int("limit", -1, "Stop extracting after this many objects")
+	charset = flag.String("charset", "", "Character set of the input, e.g. shift_jis. By default, it is detected from the input")
 
 	possibleUserAgents = []string{
 		"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:86.0) Gecko/20100101 Firefox/86.0",
@@ -50,11 +52,21 @@ func main() {
 		return
 	}
 
+	// An unknown charset would otherwise be ignored and the encoding detected from the input instead
+	if *charset != "" {
+		if _, err := htmlindex.Get(*charset); err != nil {
+			log.Fatalf("Unknown character set %q: %s", *charset, err.Error())
+		}
+	}
+
 	var sourceArg = flag.Arg(0)
 
 	var (
 		keys   []string
 		reader io.Reader
+
+		// contentType is the Content-Type header of the response, if the input was downloaded
+		contentType string
 	)
 
 	// Determine where to read data from
@@ -84,6 +96,7 @@ func main() {
 			defer resp.Body.Close()
 
 			reader = resp.Body
+			contentType = resp.Header.Get("Content-Type")
 		} else {
 			// Seems like we got a file name
 			f, err := os.Open(sourceArg)
@@ -96,6 +109,15 @@ func main() {
 		}
 	}
 
+	// Convert the input to UTF-8, an explicit charset takes precedence over the Content-Type header
+	if *charset != "" {
+		contentType = "text/plain; charset=" + *charset
+	}
+	reader, _, err := jsonextract.DecodeReader(reader, contentType)
+	if err != nil {
+		log.Fatalln("Detecting character set:", err.Error())
+	}
+
 	// First argument was the URL/file, everything else is keys
 	keys = flag.Args()[1:]
 
@@ -118,8 +140,6 @@ func main() {
 		return nil
 	}
 
-	var err error
-
 	// If no keys are given, we extract all objects and print them
 	if len(keys) == 0 {
 		// This also prints arrays, while Objects wouldn't do that
@@ -23,8 +23,9 @@
 //     	"bin": 0b10101,
 //     	bigint: 21n,
 //
-//     	// NaN will be converted to null. Infinity values are however not supported
+//     	// NaN will be converted to null. Infinity is converted to null by default
 //     	"num2": NaN,
+//     	"num3": -Infinity,
 //
 //     	// Undefined will be interpreted as null
 //     	"udef": undefined,
@@ -39,4 +40,14 @@
 //
 // Objects is a high-level function for easily extracting certain objects no matter their position within any other object.
 // Reader is a lower-level function that gives you more control over how you process objects and arrays.
+// ReaderMatches additionally reports where each object was found in the input.
+// HTMLReader only looks at the scripts of an HTML page.
+// LinkedData returns the schema.org JSON-LD items of an HTML page, optionally filtered by their @type.
+// Microdata does the same for items that are described with itemscope and itemprop attributes.
+//
+// ReaderWithOptions and ObjectsWithOptions allow configuring how forgiving the conversion is using ExtractOptions,
+// e.g. to only accept objects that are already valid JSON. ExtractOptions can also make them look for objects
+// in places where they are encoded as strings, like JSON.parse("...") calls or string values that contain JSON.
+//
+// All functions expect UTF-8 input. DecodeReader converts input in other encodings, e.g. Shift_JIS or UTF-16, to UTF-8.
 package jsonextract
@@ -0,0 +1,76 @@
+package jsonextract
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// errNotEncoded is returned from the callback of descendStrings if a string contains more than an encoded object
+var errNotEncoded = errors.New("string is not an encoded object")
+
+// descendStrings extracts objects from the string values within parent, e.g. the object in {"payload": "{\"id\": 5}"},
+// and passes them to callback. Only strings that contain nothing but one object or array and surrounding whitespace are used.
+// Since the objects are not directly part of the input, their matches have the position of parent.
+// It returns whether the callback returned ErrStop
+func descendStrings(ctx context.Context, parent Match, opts ExtractOptions, callback MatchCallback) (stopped bool, err error) {
+	var parentPos = position{offset: parent.StartOffset, line: parent.Line, column: parent.Column}
+
+	err = walkStrings(parent.Data, func(value []byte) error {
+		s, err := decodeJSONKey(value)
+		if err != nil {
+			return err
+		}
+
+		var trimmed = strings.TrimSpace(s)
+		if trimmed == "" || trimmed[0] != openObject && trimmed[0] != openArray {
+			return nil
+		}
+
+		// The first match must be the entire string, otherwise there is other text around it
+		var (
+			start = int64(len(s) - len(strings.TrimLeftFunc(s, unicode.IsSpace)))
+			end   = start + int64(len(trimmed))
+			first = true
+		)
+
+		// Objects within this string might contain encoded strings themselves, which are handled by this call
+		stopped, err = readWithin(ctx, strings.NewReader(s), opts, func(m *Match) error {
+			if first {
+				first = false
+				if m.StartOffset != start || m.EndOffset != end {
+					return errNotEncoded
+				}
+			}
+
+			m.moveTo(parentPos, int(parent.EndOffset-parent.StartOffset))
+			return nil
+		}, callback)
+		if err == errNotEncoded {
+			return nil
+		}
+		if err == nil && stopped {
+			return ErrStop
+		}
+		return err
+	})
+	if err == ErrStop {
+		err = nil
+	}
+
+	return stopped, err
+}
+
+// walkStrings calls fn for every string value within the JSON value b. Object keys are not passed to fn
+func walkStrings(b []byte, fn func(value []byte) error) error {
+	switch b[0] {
+	case '"':
+		return fn(b)
+	case '{', '[':
+		return walkJSON(b, func(_ string, value []byte, _ int) error {
+			return walkStrings(value, fn)
+		})
+	}
+	return nil
+}
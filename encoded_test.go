@@ -0,0 +1,102 @@
+package jsonextract
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReaderDescendStrings(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []json.RawMessage
+	}{
+		{
+			`{"payload": "{\"id\": 5, \"list\": \"[1, 2]\"}", "other": "[not an object"}`,
+			[]json.RawMessage{
+				[]byte(`{"payload":"{\"id\": 5, \"list\": \"[1, 2]\"}","other":"[not an object"}`),
+				[]byte(`{"id":5,"list":"[1, 2]"}`),
+				[]byte(`[1,2]`),
+			},
+		},
+		{
+			`[" {a: 'js'} ", "text {b: 1}", {"{\"key\": 1}": 2}]`,
+			[]json.RawMessage{
+				[]byte(`[" {a: 'js'} ","text {b: 1}",{"{\"key\": 1}":2}]`),
+				[]byte(`{"a":"js"}`),
+			},
+		},
+		{
+			// Strings with other text after the object or more than one object are not encoded objects
+			`["{\"a\": 1} and text", "[1] [2]", "{invalid [3]}", "[4]\n"]`,
+			[]json.RawMessage{
+				[]byte(`["{\"a\": 1} and text","[1] [2]","{invalid [3]}","[4]\n"]`),
+				[]byte(`[4]`),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var got []json.RawMessage
+		err := ReaderWithOptions(context.Background(), strings.NewReader(tt.input), ExtractOptions{DescendStrings: true}, func(m Match) error {
+			got = append(got, m.Data)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReaderWithOptions(%q) = %v, want %v", tt.input, convert(got), convert(tt.want))
+		}
+	}
+}
+
+func TestReaderDescendStringsStop(t *testing.T) {
+	var calls int
+	err := ReaderWithOptions(context.Background(), strings.NewReader(`["[1]", "[2]"] [3]`), ExtractOptions{DescendStrings: true}, func(m Match) error {
+		calls++
+		if string(m.Data) == `[1]` {
+			if m.StartOffset != 0 || m.EndOffset != 14 {
+				t.Errorf("got position %d-%d, want the position of the outer array", m.StartOffset, m.EndOffset)
+			}
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("callback was called %d times, want 2", calls)
+	}
+}
+
+func TestObjectsDescendStrings(t *testing.T) {
+	var ids []int
+
+	err := ObjectsWithOptions(context.Background(), strings.NewReader(`{"payload":"{\"id\":5,\"nested\":\"{\\\"id\\\":6}\"}"}`), ExtractOptions{DescendStrings: true}, []ObjectOption{
+		{
+			Keys: []string{"id"},
+			Callback: func(b []byte) error {
+				var v struct {
+					ID int `json:"id"`
+				}
+				if err := json.Unmarshal(b, &v); err != nil {
+					return err
+				}
+				ids = append(ids, v.ID)
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(ids, []int{5, 6}) {
+		t.Errorf("got ids %v, want [5 6]", ids)
+	}
+}
@@ -0,0 +1,204 @@
+package jsonextract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// constExpr is a constant expression like "a" + "b" or 60 * 60 that is folded while it is read
+type constExpr struct {
+	// start is the offset in the output where the expression starts, end is where it currently ends
+	start, end int
+
+	// operands are joined by ops, there is one operator less than operands unless the next operand is still missing
+	operands []constValue
+	ops      []byte
+}
+
+// pending returns whether the last operator of e still needs an operand
+func (e *constExpr) pending() bool {
+	return len(e.ops) > 0 && len(e.ops) == len(e.operands)
+}
+
+// reset removes all operands and operators from e
+func (e *constExpr) reset() {
+	e.operands, e.ops = e.operands[:0], e.ops[:0]
+}
+
+// errMissingOperand returns the error for an operator that is not followed by an operand
+func (e *constExpr) errMissingOperand() error {
+	return fmt.Errorf("operator %q must be followed by a string or number constant", e.ops[len(e.ops)-1])
+}
+
+// addOperator adds op to e. If it is the first operator, the expression starts with the value at valueStart in out
+func (e *constExpr) addOperator(out []byte, valueStart int, op byte) error {
+	if len(e.operands) == 0 {
+		operand, err := parseConstValue(out[valueStart:])
+		if err != nil {
+			return err
+		}
+
+		e.start = valueStart
+		e.operands = append(e.operands, operand)
+	}
+
+	e.ops = append(e.ops, op)
+	e.end = len(out)
+
+	return nil
+}
+
+// addOperand adds the value at operandStart in buf to e and replaces the expression in buf with its result, which is also returned
+func (e *constExpr) addOperand(buf *bytes.Buffer, operandStart int, concat, arithmetic bool) ([]byte, error) {
+	// Only numbers can have a sign, which is part of the number
+	if operandStart != e.end {
+		return nil, fmt.Errorf("unexpected sign before operand")
+	}
+
+	operand, err := parseConstValue(buf.Bytes()[operandStart:])
+	if err != nil {
+		return nil, err
+	}
+	e.operands = append(e.operands, operand)
+
+	result, err := foldConstants(e.operands, e.ops, concat, arithmetic)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := result.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	buf.Truncate(e.start)
+	buf.Write(text)
+	e.end = buf.Len()
+
+	return text, nil
+}
+
+// constValue is a string or number that is an operand of a constant expression like "a" + "b" or 60 * 60
+type constValue struct {
+	str      string
+	num      float64
+	isString bool
+}
+
+// parseConstValue decodes the JSON string or number b. Integers that can't be represented exactly as float64,
+// e.g. 18446744073709551617, are rejected, as the result would silently be different from the input
+func parseConstValue(b []byte) (v constValue, err error) {
+	if b[0] == '"' {
+		v.isString = true
+		err = json.Unmarshal(b, &v.str)
+		return
+	}
+
+	v.num, err = strconv.ParseFloat(string(b), 64)
+	if err != nil || bytes.ContainsAny(b, ".eE") {
+		return
+	}
+
+	i, ok := new(big.Int).SetString(string(b), 10)
+	if !ok {
+		return v, fmt.Errorf("invalid integer %q in constant expression", string(b))
+	}
+	if _, acc := new(big.Float).SetInt(i).Float64(); acc != big.Exact {
+		return v, fmt.Errorf("integer %s in constant expression can't be represented exactly", string(b))
+	}
+	return
+}
+
+// String returns v converted to a string, just like JavaScript would do it
+func (v constValue) String() string {
+	if v.isString {
+		return v.str
+	}
+	return formatJSNumber(v.num)
+}
+
+// MarshalJSON returns the JSON representation of v
+func (v constValue) MarshalJSON() ([]byte, error) {
+	if v.isString {
+		return json.Marshal(v.str)
+	}
+	if math.IsInf(v.num, 0) || math.IsNaN(v.num) {
+		return nil, fmt.Errorf("result %v of constant expression is not a finite number", v.num)
+	}
+	return []byte(formatJSNumber(v.num)), nil
+}
+
+// foldConstants evaluates operands joined by ops just like JavaScript does. The operators * and / take precedence over + and -.
+// Joining strings with '+' is only allowed if concat is set, operators on two numbers only if arithmetic is set
+func foldConstants(operands []constValue, ops []byte, concat, arithmetic bool) (result constValue, err error) {
+	// First evaluate all multiplications and divisions, which leaves terms that are joined by + and -
+	var (
+		terms    = []constValue{operands[0]}
+		termOps  []byte
+		lastTerm = &terms[0]
+	)
+	for i, op := range ops {
+		var next = operands[i+1]
+
+		if op == '+' || op == '-' {
+			terms = append(terms, next)
+			termOps = append(termOps, op)
+			lastTerm = &terms[len(terms)-1]
+			continue
+		}
+
+		if lastTerm.isString || next.isString {
+			return result, fmt.Errorf("cannot use %q on strings", op)
+		}
+		if !arithmetic {
+			return result, fmt.Errorf("arithmetic with numbers is not allowed")
+		}
+
+		if op == '*' {
+			lastTerm.num *= next.num
+		} else {
+			lastTerm.num /= next.num
+		}
+	}
+
+	result = terms[0]
+	for i, op := range termOps {
+		var next = terms[i+1]
+
+		switch {
+		case op == '+' && (result.isString || next.isString):
+			if !concat {
+				return result, fmt.Errorf("string concatenation is not allowed")
+			}
+			result = constValue{str: result.String() + next.String(), isString: true}
+		case result.isString || next.isString:
+			return result, fmt.Errorf("cannot use %q on strings", op)
+		case !arithmetic:
+			return result, fmt.Errorf("arithmetic with numbers is not allowed")
+		case op == '+':
+			result.num += next.num
+		default:
+			result.num -= next.num
+		}
+	}
+
+	return result, nil
+}
+
+// formatJSNumber formats f the way JavaScript converts numbers to strings
+func formatJSNumber(f float64) string {
+	if abs := math.Abs(f); abs == 0 || abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// JavaScript doesn't pad the exponent with zeros, e.g. 1e-7 instead of 1e-07
+	var s = strconv.FormatFloat(f, 'e', -1, 64)
+	var e = strings.IndexByte(s, 'e')
+
+	return s[:e+2] + strings.TrimLeft(s[e+2:], "0")
+}
@@ -0,0 +1,62 @@
+package jsonextract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReadJSObjectFolding(t *testing.T) {
+	runConversionTests(t, []conversionTest{
+		{arg: `{label: "a" + "b"}`, want: `{"label":"ab"}`},
+		{arg: `{url: "https://" + 'example.com' + "/api"}`, want: `{"url":"https://example.com/api"}`},
+		{arg: "[`a` + \"b\", \"c\" + `d${e}`]", opts: ExtractOptions{Templates: TemplateVerbatim}, want: `["ab","cd${e}"]`},
+		{arg: `["\"" + "\n"]`, want: `["\"\n"]`},
+		{arg: `["a" + 1, 1 + "a", "a" + -1.5]`, want: `["a1","1a","a-1.5"]`},
+		{arg: `["a" + 0x10 + 1e21 + 1e-7]`, want: `["a161e+211e-7"]`},
+		{arg: `[1 + 2 + "a"]`, opts: ExtractOptions{Arithmetic: true}, want: `["3a"]`},
+		{arg: `["a" + 1 + 2]`, opts: ExtractOptions{Arithmetic: true}, want: `["a12"]`},
+		{arg: `["a" + 2 * 3]`, opts: ExtractOptions{Arithmetic: true}, want: `["a6"]`},
+		{arg: `{day: 60 * 60 * 24, half: 1 / 2, n: -1 - -2}`, opts: ExtractOptions{Arithmetic: true}, want: `{"day":86400,"half":0.5,"n":1}`},
+		{arg: `[1 + 2 * 3 - 4 / 2]`, opts: ExtractOptions{Arithmetic: true}, want: `[5]`},
+		{arg: `[0.1 + 0.2]`, opts: ExtractOptions{Arithmetic: true}, want: `[0.30000000000000004]`},
+		{arg: `[1 + 2]`, wantErr: true},
+		{arg: `[60 * 60]`, wantErr: true},
+		{arg: `[9007199254740993 - 1]`, opts: ExtractOptions{Arithmetic: true}, wantErr: true},
+		{arg: `[18446744073709551617 + 0]`, opts: ExtractOptions{Arithmetic: true}, wantErr: true},
+		{arg: `["id" + 18446744073709551617]`, wantErr: true},
+		{arg: `[9007199254740992 - 1, 18446744073709551616 + 0, -1e300 * 2]`, opts: ExtractOptions{Arithmetic: true}, want: `[9007199254740991,18446744073709552000,-2e+300]`},
+		{arg: `[1 / 0]`, opts: ExtractOptions{Arithmetic: true}, wantErr: true},
+		{arg: `["a" - "b"]`, opts: ExtractOptions{Arithmetic: true}, wantErr: true},
+		{arg: `["a" * 2]`, opts: ExtractOptions{Arithmetic: true}, wantErr: true},
+		{arg: `{url: "https://" + host + "/api"}`, wantErr: true},
+		{arg: `["a" + ]`, wantErr: true},
+		{arg: `["a" + {}]`, wantErr: true},
+		{arg: `["a" + -"b"]`, wantErr: true},
+		{arg: `["a" + 5n]`, wantErr: true},
+		{arg: `[[1] + "a"]`, wantErr: true},
+		{arg: `["a" + "b"]`, opts: ExtractOptions{DisallowConcatenation: true}, wantErr: true},
+		{arg: `[1 + 2]`, opts: ExtractOptions{Strict: true, Arithmetic: true}, wantErr: true},
+	})
+}
+
+func Test_formatJSNumber(t *testing.T) {
+	tests := []struct {
+		arg  float64
+		want string
+	}{
+		{0, "0"},
+		{15, "15"},
+		{-2.5, "-2.5"},
+		{1e6, "1000000"},
+		{1e20, "100000000000000000000"},
+		{1e21, "1e+21"},
+		{1.5e-7, "1.5e-7"},
+		{0.000001, "0.000001"},
+		{math.MaxFloat64, "1.7976931348623157e+308"},
+	}
+	for _, tt := range tests {
+		if got := formatJSNumber(tt.arg); got != tt.want {
+			t.Errorf("formatJSNumber(%v) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
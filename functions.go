@@ -0,0 +1,244 @@
+package jsonextract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FunctionConverter converts a function call or constructor like new Date(0) to a JSON value.
+// args contains the arguments of the call, which were already converted to JSON.
+// If an error is returned, the object that contains the call is skipped.
+type FunctionConverter func(args []json.RawMessage) (json.RawMessage, error)
+
+// callFrame is a function call whose arguments are currently being read by readJSObject
+type callFrame struct {
+	convert FunctionConverter
+
+	// start is the offset of the arguments in the output, they are written like an array
+	start int
+
+	// depth is the number of brackets that were open when the call started
+	depth int
+}
+
+// result converts the call with the JSON array args, which contains its arguments
+func (c callFrame) result(args []byte) (json.RawMessage, error) {
+	var values []json.RawMessage
+	if err := json.Unmarshal(args, &values); err != nil {
+		return nil, err
+	}
+
+	result, err := c.convert(values)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(result) {
+		return nil, fmt.Errorf("function returned invalid JSON %q", string(result))
+	}
+
+	return result, nil
+}
+
+// maxArrayLength is the largest length that is accepted for Array(length)
+const maxArrayLength = 1 << 16
+
+// defaultFunctions are the converters used if ExtractOptions.Functions is nil. They must not be modified
+var defaultFunctions = map[string]FunctionConverter{
+	"new Date":      convertDate,
+	"Number":        convertNumber,
+	"new Number":    convertNumber,
+	"String":        convertString,
+	"new String":    convertString,
+	"Boolean":       convertBoolean,
+	"new Boolean":   convertBoolean,
+	"Array":         convertArray,
+	"new Array":     convertArray,
+	"Object":        convertIdentity,
+	"new Object":    convertIdentity,
+	"Object.freeze": convertIdentity,
+	"Object.seal":   convertIdentity,
+}
+
+// DefaultFunctions returns the converters for function calls that are used by default.
+// These are the constructor new Date, Number, String, Boolean, Array and Object with and without new,
+// Object.freeze and Object.seal. Date without new is not supported, as it returns the current time.
+//
+// The returned map can be modified, e.g. to add converters for site-specific functions:
+//
+//	var functions = jsonextract.DefaultFunctions()
+//	functions["toId"] = func(args []json.RawMessage) (json.RawMessage, error) { ... }
+//
+//	err := jsonextract.ReaderWithOptions(ctx, r, jsonextract.ExtractOptions{Functions: functions}, callback)
+func DefaultFunctions() map[string]FunctionConverter {
+	var functions = make(map[string]FunctionConverter, len(defaultFunctions))
+	for name, fn := range defaultFunctions {
+		functions[name] = fn
+	}
+	return functions
+}
+
+// convertDate converts a date to a string in the format used by JavaScript, e.g. "2021-03-31T09:12:00.000Z".
+// Dates can be created from a timestamp in milliseconds, from their components (always in UTC) or from a string, which is kept as is
+func convertDate(args []json.RawMessage) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("cannot convert a date without arguments, it would be the current time")
+	}
+
+	if len(args) == 1 && args[0][0] == '"' {
+		return args[0], nil
+	}
+
+	var components [7]float64
+	for i, arg := range args {
+		if i == len(components) {
+			break
+		}
+
+		f, err := strconv.ParseFloat(string(arg), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date argument %s", string(arg))
+		}
+		components[i] = f
+	}
+
+	var t time.Time
+	if len(args) == 1 {
+		var ms = int64(components[0])
+		t = time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+	} else {
+		// Months start at 0, the day defaults to 1
+		if len(args) < 3 {
+			components[2] = 1
+		}
+		t = time.Date(int(components[0]), time.Month(components[1]+1), int(components[2]), int(components[3]),
+			int(components[4]), int(components[5]), int(components[6])*int(time.Millisecond), time.UTC)
+	}
+
+	return json.Marshal(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+}
+
+// convertNumber converts its argument to a number. Values that cannot be converted result in null, just like NaN
+func convertNumber(args []json.RawMessage) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return []byte("0"), nil
+	}
+
+	switch arg := args[0]; {
+	case string(arg) == "true":
+		return []byte("1"), nil
+	case string(arg) == "false" || string(arg) == "null":
+		return []byte("0"), nil
+	case arg[0] == '"':
+		var s string
+		if err := json.Unmarshal(arg, &s); err != nil {
+			return nil, err
+		}
+
+		if s = strings.TrimSpace(s); s == "" {
+			return []byte("0"), nil
+		}
+
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return []byte("null"), nil
+		}
+		return []byte(formatJSNumber(f)), nil
+	case arg[0] == '{' || arg[0] == '[':
+		return []byte("null"), nil
+	default:
+		return arg, nil
+	}
+}
+
+// convertString converts its argument to a string. Objects and arrays are not supported
+func convertString(args []json.RawMessage) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return []byte(`""`), nil
+	}
+
+	switch arg := args[0]; arg[0] {
+	case '"':
+		return arg, nil
+	case '{', '[':
+		return nil, fmt.Errorf("cannot convert %s to a string", string(arg))
+	case 't', 'f', 'n':
+		// true, false and null
+		return json.Marshal(string(arg))
+	default:
+		f, err := strconv.ParseFloat(string(arg), 64)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(formatJSNumber(f))
+	}
+}
+
+// convertBoolean converts its argument to a boolean
+func convertBoolean(args []json.RawMessage) (json.RawMessage, error) {
+	if len(args) == 0 {
+		return []byte("false"), nil
+	}
+
+	switch arg := args[0]; {
+	case string(arg) == "false" || string(arg) == "null" || string(arg) == `""`:
+		return []byte("false"), nil
+	case arg[0] == '-' || '0' <= arg[0] && arg[0] <= '9':
+		f, err := strconv.ParseFloat(string(arg), 64)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatBool(f != 0)), nil
+	default:
+		return []byte("true"), nil
+	}
+}
+
+// convertArray returns an array of its arguments. If there is only one number argument,
+// it is the length of the array, which is then filled with null
+func convertArray(args []json.RawMessage) (json.RawMessage, error) {
+	if len(args) == 1 {
+		if f, err := strconv.ParseFloat(string(args[0]), 64); err == nil {
+			if f < 0 || f > maxArrayLength || f != math.Trunc(f) {
+				return nil, fmt.Errorf("invalid array length %s", string(args[0]))
+			}
+
+			var n = int(f)
+
+			var b = make([]byte, 0, 2+5*n)
+			b = append(b, '[')
+			for i := 0; i < n; i++ {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, "null"...)
+			}
+			return append(b, ']'), nil
+		}
+	}
+
+	var b = []byte{'['}
+	for i, arg := range args {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, arg...)
+	}
+	return append(b, ']'), nil
+}
+
+// convertIdentity returns its only argument, e.g. Object.freeze({...}) is converted to the object.
+// Without arguments, an empty object is returned
+func convertIdentity(args []json.RawMessage) (json.RawMessage, error) {
+	switch len(args) {
+	case 0:
+		return []byte("{}"), nil
+	case 1:
+		return args[0], nil
+	default:
+		return nil, fmt.Errorf("expected one argument, but got %d", len(args))
+	}
+}
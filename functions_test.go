@@ -0,0 +1,68 @@
+package jsonextract
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadJSObjectFunctions(t *testing.T) {
+	var custom = DefaultFunctions()
+	custom["site.toId"] = func(args []json.RawMessage) (json.RawMessage, error) {
+		return json.Marshal(fmt.Sprintf("id-%s", args[0]))
+	}
+
+	runConversionTests(t, []conversionTest{
+		{arg: `{created: new Date(1617181920000)}`, want: `{"created":"2021-03-31T09:12:00.000Z"}`},
+		{arg: `[new Date(2021, 2, 31, 9, 12), new Date("2021-03-31"), new Date(0)]`, want: `["2021-03-31T09:12:00.000Z","2021-03-31","1970-01-01T00:00:00.000Z"]`},
+		{arg: `[Date(0)]`, wantErr: true},
+		{arg: `[new Date()]`, wantErr: true},
+		{arg: `{id: String(123), s: String('a'), b: String(true), f: String(1.50)}`, want: `{"id":"123","s":"a","b":"true","f":"1.5"}`},
+		{arg: `[Number("42"), Number(" 1.5 "), Number("abc"), Number(true), Number()]`, want: `[42,1.5,null,1,0]`},
+		{arg: `[Boolean(0), Boolean(""), Boolean("a"), Boolean(-1), Boolean({})]`, want: `[false,false,true,true,true]`},
+		{arg: `[Array(1, "a", [2]), Array(3), new Array()]`, want: `[[1,"a",[2]],[null,null,null],[]]`},
+		{arg: `[new String(1), new Number("2"), new Boolean(0), new Object({a: 1})]`, want: `["1",2,false,{"a":1}]`},
+		{arg: `[new Object.freeze({})]`, wantErr: true},
+		{arg: `{config: Object.freeze({a: [1, 2,], b: new Date(0)},)}`, want: `{"config":{"a":[1,2],"b":"1970-01-01T00:00:00.000Z"}}`},
+		{arg: `[Object . seal ( [1] )]`, want: `[[1]]`},
+		{arg: `[-Number("5"), Number(-5)]`, want: `[-5,-5]`},
+		{arg: `[String(Number("7"))]`, want: `["7"]`},
+		{arg: `[Unknown(1)]`, wantErr: true},
+		{arg: `[new Date]`, wantErr: true},
+		{arg: `[window.Date(0)]`, wantErr: true},
+		{arg: `[String([1])]`, wantErr: true},
+		{arg: `[String(1]`, wantErr: true},
+		{arg: `[String(1), 2)]`, wantErr: true},
+		{arg: `[Array(1e10)]`, wantErr: true},
+		{arg: `[site.toId(5)]`, opts: ExtractOptions{Functions: custom}, want: `["id-5"]`},
+		{arg: `[String(5)]`, opts: ExtractOptions{Functions: map[string]FunctionConverter{}}, wantErr: true},
+		{arg: `[String(5)]`, opts: ExtractOptions{Strict: true}, wantErr: true},
+	})
+}
+
+func TestReaderFunctionsMatch(t *testing.T) {
+	var input = `x = {a: new Date(0), b: {c: 1}}`
+
+	var got []Match
+	err := ObjectsWithOptions(context.Background(), strings.NewReader(input), ExtractOptions{}, []ObjectOption{
+		{
+			Keys: []string{"c"},
+			MatchCallback: func(m Match) error {
+				got = append(got, m)
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("got %d matches, want 1", len(got))
+	}
+	if string(got[0].Raw) != `{c: 1}` || got[0].StartOffset != 24 {
+		t.Errorf("got match %q at offset %d, want {c: 1} at offset 24", got[0].Raw, got[0].StartOffset)
+	}
+}
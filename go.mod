@@ -2,4 +2,7 @@ module github.com/xarantolus/jsonextract
 
 go 1.16
 
-require github.com/tdewolff/parse/v2 v2.8.5
+require (
+	github.com/tdewolff/parse/v2 v2.8.5
+	golang.org/x/text v0.13.0
+)
@@ -0,0 +1,182 @@
+package jsonextract
+
+import "fmt"
+
+// grammarState describes which tokens can come next in the output of objectReader.read
+type grammarState uint8
+
+const (
+	// expectValue is the state after a colon, where only a value can come next
+	expectValue grammarState = iota
+	// expectElement is the state after a comma in an array or the arguments of a function call, where a value or
+	// the closing bracket can come next. A closing bracket after a comma is a trailing comma, which is removed.
+	// expectFirstElement is the same state right after the opening bracket
+	expectElement
+	expectFirstElement
+	// expectSigned is the state after a sign, where only a number can come next
+	expectSigned
+	// expectKey is the state after a comma in an object, where a key or the closing brace can come next.
+	// expectFirstKey is the same state right after the opening brace
+	expectKey
+	expectFirstKey
+	// expectColon is the state after a key
+	expectColon
+	// expectSeparator is the state after a value, where a comma or a closing bracket can come next
+	expectSeparator
+	// expectClose is the state after a comma right after an opening bracket, e.g. in [,], where only the closing bracket can come next.
+	// The comma is removed like a trailing comma, which results in an empty array
+	expectClose
+)
+
+// valueKind describes where a value can be used. Values like true can only be used as values
+type valueKind uint8
+
+const (
+	// valueKey is set for values that are converted to strings or can start an expression that results in one.
+	// They can also be the key of an object
+	valueKey valueKind = 1 << iota
+	// valueSigned is set for numbers and values that replace them, e.g. NaN. They can follow a sign
+	valueSigned
+)
+
+// jsonGrammar tracks the structure of the output of objectReader.read. Reading stops at the first token
+// that can't be part of valid JSON, e.g. the second word in { hello world }, instead of reading
+// until the brackets are closed. Without it, a single opening bracket that is not the start of an object,
+// e.g. in a log file, would make the reader read the rest of the input
+type jsonGrammar struct {
+	// open contains the brackets that are currently open, '(' is the start of the arguments of a function call
+	open []byte
+
+	next grammarState
+}
+
+// reset starts reading a new object
+func (g *jsonGrammar) reset() {
+	g.open, g.next = g.open[:0], expectValue
+}
+
+// restore sets the state of g to the one after out, which contains the brackets at the offsets in brackets.
+// out must not contain the arguments of a function call
+func (g *jsonGrammar) restore(out []byte, brackets []int, next grammarState) {
+	g.open = g.open[:0]
+	for _, off := range brackets {
+		switch c := out[off]; c {
+		case '{', '[':
+			g.open = append(g.open, c)
+		default:
+			g.open = g.open[:len(g.open)-1]
+		}
+	}
+	g.next = next
+}
+
+// valueNext returns whether a value can come next
+func (g *jsonGrammar) valueNext() bool {
+	return g.next == expectValue || g.next == expectElement || g.next == expectFirstElement
+}
+
+// value records a value of the given kind
+func (g *jsonGrammar) value(kind valueKind) error {
+	switch {
+	case (g.next == expectKey || g.next == expectFirstKey) && kind&valueKey != 0:
+		g.next = expectColon
+	case g.valueNext() || g.next == expectSigned && kind&valueSigned != 0:
+		g.next = expectSeparator
+	default:
+		return g.errUnexpected("value")
+	}
+	return nil
+}
+
+// sign records the sign c in front of a number. A plus sign is removed, so it can also follow another sign, e.g. in - +1
+func (g *jsonGrammar) sign(c byte) error {
+	if !g.valueNext() && (g.next != expectSigned || c != '+') {
+		return g.errUnexpected("sign")
+	}
+	g.next = expectSigned
+	return nil
+}
+
+// openBracket records an opening bracket c, which is '{', '[' or '('.
+// A function call can have a sign, e.g. -Number("5"), as its result might be a number
+func (g *jsonGrammar) openBracket(c byte) error {
+	if !g.valueNext() && (g.next != expectSigned || c != '(') {
+		return g.errUnexpected(fmt.Sprintf("%q", c))
+	}
+
+	g.open = append(g.open, c)
+	if c == '{' {
+		g.next = expectFirstKey
+	} else {
+		g.next = expectFirstElement
+	}
+	return nil
+}
+
+// closeBracket records a closing bracket c, which is '}', ']' or ')'
+func (g *jsonGrammar) closeBracket(c byte) error {
+	var top byte
+	if len(g.open) > 0 {
+		top = g.open[len(g.open)-1]
+	}
+
+	var ok = g.next == expectSeparator || g.next == expectClose
+	switch c {
+	case '}':
+		ok = top == '{' && (ok || g.next == expectKey || g.next == expectFirstKey)
+	case ']':
+		ok = top == '[' && (ok || g.next == expectElement || g.next == expectFirstElement)
+	case ')':
+		ok = top == '(' && (ok || g.next == expectElement || g.next == expectFirstElement)
+	}
+	if !ok {
+		return g.errUnexpected(fmt.Sprintf("%q", c))
+	}
+
+	g.open = g.open[:len(g.open)-1]
+	g.next = expectSeparator
+	return nil
+}
+
+// punctuator records the punctuator c, which is not a bracket
+func (g *jsonGrammar) punctuator(c byte) error {
+	switch {
+	case c == ',' && g.next == expectSeparator && len(g.open) > 0:
+		if g.open[len(g.open)-1] == '{' {
+			g.next = expectKey
+		} else {
+			g.next = expectElement
+		}
+	case c == ',' && (g.next == expectFirstKey || g.next == expectFirstElement):
+		g.next = expectClose
+	case c == ':' && g.next == expectColon:
+		g.next = expectValue
+	case c == '+' || c == '-':
+		return g.sign(c)
+	default:
+		return g.errUnexpected(fmt.Sprintf("%q", c))
+	}
+	return nil
+}
+
+// errUnexpected returns the error for a token that can't come next
+func (g *jsonGrammar) errUnexpected(token string) error {
+	var expected string
+	switch g.next {
+	case expectValue:
+		expected = "a value"
+	case expectElement, expectFirstElement:
+		expected = "a value or closing bracket"
+	case expectSigned:
+		expected = "a number"
+	case expectKey, expectFirstKey:
+		expected = "a key or closing brace"
+	case expectColon:
+		expected = "a colon"
+	case expectSeparator:
+		expected = "a comma or closing bracket"
+	case expectClose:
+		expected = "a closing bracket"
+	}
+	return fmt.Errorf("unexpected %s, expected %s", token, expected)
+}
@@ -0,0 +1,275 @@
+package jsonextract
+
+import (
+	"bytes"
+	"context"
+	stdhtml "html"
+	"io"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/html"
+)
+
+// HTMLElement describes the HTML element a value was found in
+type HTMLElement struct {
+	// Tag is the lowercase name of the element, e.g. "script"
+	Tag string
+
+	// ID and Type are the id and type attributes of the element. They are empty if the element doesn't have them
+	ID, Type string
+
+	// Attribute is the name of the attribute the value was found in, e.g. "data-props".
+	// It is empty if the value was found in the contents of the element
+	Attribute string
+}
+
+// htmlAttribute is an attribute of the element whose start tag is currently being read
+type htmlAttribute struct {
+	key string
+
+	// value is the value of the attribute without quotes, offset is its offset in the page
+	value  []byte
+	offset int
+}
+
+// scriptTypes are the types of scripts that contain JavaScript. Types that end with "json", e.g. application/ld+json, are also searched
+var scriptTypes = map[string]bool{
+	"":                         true,
+	"module":                   true,
+	"text/javascript":          true,
+	"application/javascript":   true,
+	"application/x-javascript": true,
+	"text/ecmascript":          true,
+	"application/ecmascript":   true,
+	"importmap":                true,
+	"speculationrules":         true,
+}
+
+// voidElements are elements that have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// HTMLReader works like ReaderWithOptions, but reads an HTML page and only extracts values from the contents of its <script> elements.
+// This avoids matches in text and styles, e.g. [0] in the text of a page. Only scripts with JavaScript or JSON content are searched,
+// e.g. those without a type, with type="module", type="application/json" or type="application/ld+json", but not templates like type="text/x-template".
+//
+// The Element field of each match describes the script, e.g. its id attribute. The position of each match is its position in the page.
+//
+// Setting ExtractOptions.HTMLAttributes or ExtractOptions.HTMLText also searches attribute values and the text of other elements.
+// HTML entities in them are decoded first, e.g. data-props="{&quot;id&quot;:1}" results in {"id":1}. If a value contains entities,
+// its matches contain the decoded text as Raw and the position of the whole attribute value or text.
+//
+// Unlike ReaderWithOptions, HTMLReader reads the whole page into memory.
+func HTMLReader(ctx context.Context, r io.Reader, opts ExtractOptions, callback MatchCallback) (err error) {
+	return readHTML(ctx, r, opts, nil, callback)
+}
+
+// readHTML implements HTMLReader. If onScript is not nil, it is called with the content of every script
+// that is searched and its offset in the page before any matches in it are passed to callback
+func readHTML(ctx context.Context, r io.Reader, opts ExtractOptions, onScript func(content []byte, offset int), callback MatchCallback) (err error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	input := parse.NewInputBytes(page)
+	defer input.Restore()
+
+	var (
+		lex = html.NewLexer(input)
+
+		// pos is the position of page[pos.offset:] in the input
+		pos = newPosition()
+
+		// element is the element whose start tag is currently being read
+		element *HTMLElement
+		// attributes are the attributes of element that are searched if opts.HTMLAttributes is set
+		attributes []htmlAttribute
+		// script is set after the start tag of a script element was read completely
+		script *HTMLElement
+		// open contains the elements that were opened, but not yet closed. It is only kept if opts.HTMLText is set
+		open []*HTMLElement
+	)
+
+	// search extracts values from text, which starts at offset start in the page, and passes them to callback.
+	// If decode is set, HTML entities in text are decoded first
+	search := func(text []byte, start int, decode bool, element *HTMLElement) (stopped bool, err error) {
+		pos.advance(page[int(pos.offset):start])
+		if decode {
+			return readHTMLEncoded(ctx, text, pos, element, opts, callback)
+		}
+		return readHTMLText(ctx, text, pos, element, opts, callback)
+	}
+
+	// closeStartTag searches the attributes of element once all of them are known, e.g. its id
+	closeStartTag := func() (stopped bool, err error) {
+		for _, attr := range attributes {
+			var attrElement = *element
+			attrElement.Attribute = attr.key
+
+			stopped, err = search(attr.value, attr.offset, true, &attrElement)
+			if err != nil || stopped {
+				return
+			}
+		}
+		attributes = attributes[:0]
+		return false, nil
+	}
+
+	for {
+		tt, text := lex.Next()
+		switch tt {
+		case html.ErrorToken:
+			if lex.Err() == io.EOF {
+				return nil
+			}
+			return lex.Err()
+		case html.StartTagToken:
+			element = &HTMLElement{Tag: string(lex.Text())}
+			script = nil
+		case html.AttributeToken:
+			if element == nil {
+				continue
+			}
+
+			switch key := string(lex.AttrKey()); key {
+			case "id":
+				element.ID = attributeValue(lex.AttrVal())
+			case "type":
+				element.Type = attributeValue(lex.AttrVal())
+			default:
+				if !opts.HTMLAttributes {
+					continue
+				}
+
+				// The lexer is right behind the attribute, which ends with its value
+				val, quoted := unquote(lex.AttrVal())
+				var offset = input.Offset() - len(val)
+				if quoted {
+					offset--
+				}
+				attributes = append(attributes, htmlAttribute{key: key, value: val, offset: offset})
+			}
+		case html.StartTagCloseToken, html.StartTagVoidToken:
+			if element == nil {
+				continue
+			}
+
+			stopped, serr := closeStartTag()
+			if serr != nil || stopped {
+				return serr
+			}
+
+			if tt == html.StartTagCloseToken && element.Tag == "script" {
+				script = element
+			}
+			if opts.HTMLText && tt == html.StartTagCloseToken && !voidElements[element.Tag] {
+				open = append(open, element)
+			}
+			element = nil
+		case html.EndTagToken:
+			// Close the element and all elements within it that were never closed
+			var tag = string(lex.Text())
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].Tag == tag {
+					open = open[:i]
+					break
+				}
+			}
+			element, script = nil, nil
+		case html.TextToken:
+			// The lexer is right behind the text
+			var start = input.Offset() - len(text)
+
+			if script != nil {
+				var s = script
+				script = nil
+				if !isScriptType(s.Type) {
+					continue
+				}
+				if onScript != nil {
+					onScript(text, start)
+				}
+
+				stopped, serr := search(text, start, false, s)
+				if serr != nil || stopped {
+					return serr
+				}
+				continue
+			}
+
+			if !opts.HTMLText || len(open) == 0 {
+				continue
+			}
+
+			// Styles are not HTML text, scripts were already handled above
+			var parent = open[len(open)-1]
+			if parent.Tag == "style" || parent.Tag == "script" {
+				continue
+			}
+
+			stopped, serr := search(text, start, true, parent)
+			if serr != nil || stopped {
+				return serr
+			}
+		default:
+			element, script = nil, nil
+		}
+	}
+}
+
+// readHTMLText extracts all values from text, which was found at pos in the contents of element, and passes them to callback.
+// It returns whether the callback returned ErrStop
+func readHTMLText(ctx context.Context, text []byte, pos position, element *HTMLElement, opts ExtractOptions, callback MatchCallback) (stopped bool, err error) {
+	return readWithin(ctx, bytes.NewReader(text), opts, func(m *Match) error {
+		m.shift(pos)
+		m.Element = element
+		return nil
+	}, callback)
+}
+
+// readHTMLEncoded works like readHTMLText, but decodes the HTML entities in text first.
+// If text contains entities, the matches have the position of the whole text, just like the matches of descendStrings
+func readHTMLEncoded(ctx context.Context, text []byte, pos position, element *HTMLElement, opts ExtractOptions, callback MatchCallback) (stopped bool, err error) {
+	if bytes.IndexByte(text, '&') < 0 {
+		return readHTMLText(ctx, text, pos, element, opts, callback)
+	}
+
+	decoded := stdhtml.UnescapeString(string(text))
+	if !strings.ContainsAny(decoded, "{[") {
+		return false, nil
+	}
+
+	return readWithin(ctx, strings.NewReader(decoded), opts, func(m *Match) error {
+		m.moveTo(pos, len(text))
+		m.Element = element
+		return nil
+	}, callback)
+}
+
+// unquote removes the quotes around an attribute value, if there are any
+func unquote(val []byte) (unquoted []byte, quoted bool) {
+	if len(val) >= 2 && (val[0] == '"' || val[0] == '\'') && val[len(val)-1] == val[0] {
+		return val[1 : len(val)-1], true
+	}
+	return val, false
+}
+
+// attributeValue returns the decoded value of an attribute, which might be quoted
+func attributeValue(val []byte) string {
+	val, _ = unquote(val)
+	return stdhtml.UnescapeString(string(val))
+}
+
+// isScriptType returns whether a script with the type attribute typ contains JavaScript or JSON
+func isScriptType(typ string) bool {
+	// Parameters like charset don't matter
+	if i := strings.IndexByte(typ, ';'); i >= 0 {
+		typ = typ[:i]
+	}
+	typ = strings.ToLower(strings.TrimSpace(typ))
+
+	return scriptTypes[typ] || strings.HasSuffix(typ, "json")
+}
@@ -0,0 +1,140 @@
+package jsonextract
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+	<style>a[href] { color: red; }</style>
+	<script>var data = {id: 1, list: [2]};</script>
+	<script type="application/ld+json" id='ld'>
+		{"@type": "Person", "name": "A &amp; B"}
+	</script>
+	<script type="text/x-template">{"template": true}</script>
+	<script type="MODULE; charset=utf-8">f([3])</script>
+</head>
+<body data-x="{&quot;a&quot;: 1}">
+	<p>Items [4] and {"text": true}</p>
+	<script></script>
+</body>
+</html>`
+
+func TestHTMLReader(t *testing.T) {
+	type result struct {
+		data    string
+		element HTMLElement
+	}
+
+	var got []result
+	err := HTMLReader(context.Background(), strings.NewReader(testPage), ExtractOptions{}, func(m Match) error {
+		got = append(got, result{string(m.Data), *m.Element})
+
+		if source := testPage[m.StartOffset:m.EndOffset]; source != string(m.Raw) {
+			t.Errorf("offsets of %s point to %q, but Raw is %q", m.Data, source, m.Raw)
+		}
+
+		var pos = newPosition()
+		pos.advance([]byte(testPage[:m.StartOffset]))
+		if pos.line != m.Line || pos.column != m.Column {
+			t.Errorf("%s starts at %d:%d, but match reports %d:%d", m.Data, pos.line, pos.column, m.Line, m.Column)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []result{
+		{`{"id":1,"list":[2]}`, HTMLElement{Tag: "script"}},
+		{`{"@type":"Person","name":"A &amp; B"}`, HTMLElement{Tag: "script", ID: "ld", Type: "application/ld+json"}},
+		{`[3]`, HTMLElement{Tag: "script", Type: "MODULE; charset=utf-8"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HTMLReader() returned %v, want %v", got, want)
+	}
+}
+
+func TestHTMLReaderAttributes(t *testing.T) {
+	const page = `<div id="app" data-props="{&quot;id&quot;:1, &quot;tags&quot;: [&#39;a&#39;]}" data-raw='{"raw": true}' hidden>
+	<img src="x.png" data-meta="[2]">
+	<p>Text with {&quot;text&quot;: 3} and <b>[4]</b> [5]</p>
+	<style>a[href] { color: red; }</style>
+	<script type="text/x-template" data-x="[6]">{"template": true}</script>
+</div>`
+
+	type result struct {
+		data, raw string
+		element   HTMLElement
+	}
+
+	var got []result
+	err := HTMLReader(context.Background(), strings.NewReader(page), ExtractOptions{HTMLAttributes: true, HTMLText: true}, func(m Match) error {
+		got = append(got, result{string(m.Data), string(m.Raw), *m.Element})
+
+		// Matches in values with entities have the position of the whole value
+		if source := page[m.StartOffset:m.EndOffset]; source != string(m.Raw) && !strings.Contains(source, "&") {
+			t.Errorf("offsets of %s point to %q, but Raw is %q", m.Data, source, m.Raw)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []result{
+		{`{"id":1,"tags":["a"]}`, `{"id":1, "tags": ['a']}`, HTMLElement{Tag: "div", ID: "app", Attribute: "data-props"}},
+		{`{"raw":true}`, `{"raw": true}`, HTMLElement{Tag: "div", ID: "app", Attribute: "data-raw"}},
+		{`[2]`, `[2]`, HTMLElement{Tag: "img", Attribute: "data-meta"}},
+		{`{"text":3}`, `{"text": 3}`, HTMLElement{Tag: "p"}},
+		{`[4]`, `[4]`, HTMLElement{Tag: "b"}},
+		{`[5]`, `[5]`, HTMLElement{Tag: "p"}},
+		{`[6]`, `[6]`, HTMLElement{Tag: "script", Type: "text/x-template", Attribute: "data-x"}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HTMLReader() returned %v, want %v", got, want)
+	}
+}
+
+func TestHTMLReaderStop(t *testing.T) {
+	var calls int
+	err := HTMLReader(context.Background(), strings.NewReader(testPage), ExtractOptions{}, func(m Match) error {
+		calls++
+		return ErrStop
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback was called %d times after returning ErrStop, want 1", calls)
+	}
+}
+
+func Test_isScriptType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want bool
+	}{
+		{"", true},
+		{"text/javascript", true},
+		{" Module ", true},
+		{"application/json", true},
+		{"application/ld+json", true},
+		{"application/json; charset=utf-8", true},
+		{"text/x-template", false},
+		{"text/html", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			if got := isScriptType(tt.typ); got != tt.want {
+				t.Errorf("isScriptType(%q) = %v, want %v", tt.typ, got, tt.want)
+			}
+		})
+	}
+}
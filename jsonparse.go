@@ -0,0 +1,256 @@
+package jsonextract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/js"
+)
+
+// jsonParseCall is the function whose string argument is extracted if ExtractOptions.UnwrapJSONParse is set,
+// e.g. the object in JSON.parse("{\"a\": 1}")
+var jsonParseCall = []byte("JSON.parse")
+
+// errNoStringArgument is returned if the argument of a JSON.parse call is not a string literal
+var errNoStringArgument = errors.New("JSON.parse is not called with a string literal")
+
+// readJSONParseArgument returns the offset and length of the string literal passed to the JSON.parse call at the start of the data in w
+func readJSONParseArgument(w *window) (start, n int, err error) {
+	for size := 0; ; {
+		w.Fill(size)
+
+		var data = w.Bytes()
+
+		start, n, err = jsonParseArgument(data)
+		if err == errIncomplete && !w.Complete() {
+			size = 2 * len(data)
+			continue
+		}
+
+		return
+	}
+}
+
+// jsonParseArgument returns the offset and length of the string literal passed to the JSON.parse call at the start of b
+func jsonParseArgument(b []byte) (start, n int, err error) {
+	var i = skipJSONSpace(b, len(jsonParseCall))
+	if i == len(b) {
+		return 0, 0, errIncomplete
+	}
+	if b[i] != '(' {
+		return 0, 0, errNoStringArgument
+	}
+
+	i = skipJSONSpace(b, i+1)
+	if i == len(b) {
+		return 0, 0, errIncomplete
+	}
+	if b[i] != '"' && b[i] != '\'' && b[i] != '`' {
+		return 0, 0, errNoStringArgument
+	}
+
+	input := parse.NewInputBytes(b[i:])
+	// NewInputBytes might temporarily overwrite the byte after b, we must restore it
+	defer input.Restore()
+
+	lex := js.NewLexer(input)
+
+	tt, text := lex.Next()
+	switch {
+	case tt == js.StringToken || tt == js.TemplateToken:
+		return i, len(text), nil
+	case tt == js.ErrorToken && input.Offset()+maxLexerLookahead >= len(b)-i:
+		// The string literal might end after b
+		return 0, 0, errIncomplete
+	default:
+		// e.g. a template literal with substitutions
+		return 0, 0, errNoStringArgument
+	}
+}
+
+// unwrapJSONParse extracts all objects from the JavaScript string literal lit and passes them to callback.
+// Since their position within the input is not known, matches have the position of the literal in the input.
+// If the string contains a single value, its match is assigned to target, which is the variable that the result of the call is assigned to.
+// It returns whether the callback returned ErrStop
+func unwrapJSONParse(ctx context.Context, lit []byte, litPos position, target string, opts ExtractOptions, callback MatchCallback) (stopped bool, err error) {
+	content, err := unquoteJSString(lit)
+	if err != nil {
+		// Not a valid string, there's nothing to extract
+		return false, nil
+	}
+
+	var (
+		valueStart = int64(skipJSONSpace(content, 0))
+		valueEnd   = int64(skipJSSpaceBackward(content, len(content)))
+	)
+
+	return readWithin(ctx, bytes.NewReader(content), opts, func(m *Match) error {
+		if m.StartOffset == valueStart && m.EndOffset == valueEnd {
+			m.AssignedTo = target
+		}
+		m.moveTo(litPos, len(lit))
+		return nil
+	}, callback)
+}
+
+// unquoteJSString returns the content of the JavaScript string literal lit, which includes its quotes.
+// Template literals must not contain substitutions
+func unquoteJSString(lit []byte) ([]byte, error) {
+	if len(lit) < 2 {
+		return nil, fmt.Errorf("string literal %q is too short", string(lit))
+	}
+
+	var (
+		quote = lit[0]
+		s     = lit[1 : len(lit)-1]
+		out   = make([]byte, 0, len(s))
+	)
+
+	for i := 0; i < len(s); {
+		var c = s[i]
+
+		if quote == '`' && c == '$' && i+1 < len(s) && s[i+1] == '{' {
+			return nil, fmt.Errorf("template literal contains substitutions")
+		}
+
+		if c != '\\' {
+			out = append(out, c)
+			i++
+			continue
+		}
+
+		if i+1 == len(s) {
+			return nil, fmt.Errorf("string literal ends with a backslash")
+		}
+
+		var (
+			r    rune
+			size int
+			err  error
+		)
+
+		switch c = s[i+1]; c {
+		case 'n':
+			r, size = '\n', 2
+		case 'r':
+			r, size = '\r', 2
+		case 't':
+			r, size = '\t', 2
+		case 'b':
+			r, size = '\b', 2
+		case 'f':
+			r, size = '\f', 2
+		case 'v':
+			r, size = '\v', 2
+		case '0':
+			if i+2 < len(s) && '0' <= s[i+2] && s[i+2] <= '9' {
+				return nil, fmt.Errorf("octal escape sequences are not supported")
+			}
+			r, size = 0, 2
+		case 'x':
+			r, size, err = unquoteHex(s[i:], 2, 2)
+		case 'u':
+			r, size, err = unquoteUnicode(s[i:])
+		case '\r':
+			// Line continuation, which doesn't add anything to the string
+			size = 2
+			if i+2 < len(s) && s[i+2] == '\n' {
+				size = 3
+			}
+			r = -1
+		case '\n':
+			r, size = -1, 2
+		default:
+			if '1' <= c && c <= '9' {
+				return nil, fmt.Errorf("octal escape sequences are not supported")
+			}
+
+			// Any other escaped character stands for itself, e.g. \" or \/.
+			// \u2028 and \u2029 are line continuations
+			r, size = utf8.DecodeRune(s[i+1:])
+			if r == '\u2028' || r == '\u2029' {
+				r = -1
+			}
+			size++
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if r >= 0 {
+			var encoded [utf8.UTFMax]byte
+			out = append(out, encoded[:utf8.EncodeRune(encoded[:], r)]...)
+		}
+		i += size
+	}
+
+	return out, nil
+}
+
+// unquoteHex decodes the escape sequence at the start of b, which consists of a prefix like \x and digits hex digits.
+// It returns the rune and the length of the escape sequence
+func unquoteHex(b []byte, prefix, digits int) (r rune, size int, err error) {
+	if len(b) < prefix+digits {
+		return 0, 0, fmt.Errorf("escape sequence %q is too short", string(b))
+	}
+
+	for _, c := range b[prefix : prefix+digits] {
+		v, ok := hexDigit(c)
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid escape sequence %q", string(b[:prefix+digits]))
+		}
+		r = r<<4 | v
+	}
+
+	return r, prefix + digits, nil
+}
+
+// unquoteUnicode decodes the \u escape sequence at the start of b, which can be \uXXXX, \u{X} or a surrogate pair like \uD83D\uDE00
+func unquoteUnicode(b []byte) (r rune, size int, err error) {
+	if len(b) > 2 && b[2] == '{' {
+		end := bytes.IndexByte(b, '}')
+		if end < 0 || end == 3 || end > 3+6 {
+			return 0, 0, fmt.Errorf("invalid escape sequence %q", string(b))
+		}
+
+		r, _, err = unquoteHex(b[:end], 3, end-3)
+		if err == nil && r > utf8.MaxRune {
+			err = fmt.Errorf("invalid code point in escape sequence %q", string(b[:end+1]))
+		}
+		return r, end + 1, err
+	}
+
+	r, size, err = unquoteHex(b, 2, 4)
+	if err != nil || !utf16.IsSurrogate(r) {
+		return
+	}
+
+	// A surrogate pair consists of two escape sequences
+	if len(b) >= 2*size && b[size] == '\\' && b[size+1] == 'u' {
+		if low, lowSize, err := unquoteHex(b[size:], 2, 4); err == nil {
+			if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+				return pair, size + lowSize, nil
+			}
+		}
+	}
+
+	// A lone surrogate is not valid UTF-8
+	return utf8.RuneError, size, nil
+}
+
+func hexDigit(c byte) (rune, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return rune(c - '0'), true
+	case 'a' <= c && c <= 'f':
+		return rune(c-'a') + 10, true
+	case 'A' <= c && c <= 'F':
+		return rune(c-'A') + 10, true
+	}
+	return 0, false
+}
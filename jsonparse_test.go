@@ -0,0 +1,152 @@
+package jsonextract
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestUnquoteJSString(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{arg: `""`, want: ``},
+		{arg: `"{\"a\":1}"`, want: `{"a":1}`},
+		{arg: `'{"a":\'b\'}'`, want: `{"a":'b'}`},
+		{arg: "`{\"a\":\n1}`", want: "{\"a\":\n1}"},
+		{arg: `"\n\r\t\b\f\v\0\/\\"`, want: "\n\r\t\b\f\v\x00/\\"},
+		{arg: `"\x41B\u{43}\u{1F600}"`, want: "ABC\U0001F600"},
+		{arg: `"\uD83D\uDE00"`, want: "\U0001F600"},
+		{arg: `"\uD83D"`, want: "\uFFFD"},
+		{arg: "\"a\\\nb\\\r\nc\"", want: "abc"},
+		{arg: `"\x4"`, wantErr: true},
+		{arg: `"\u{110000}"`, wantErr: true},
+		{arg: `"\12"`, wantErr: true},
+		{arg: "`${a}`", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := unquoteJSString([]byte(tt.arg))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unquoteJSString(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("unquoteJSString(%q) = %q, want %q", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReaderUnwrapJSONParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []json.RawMessage
+	}{
+		{
+			`window.__DATA__ = JSON.parse("{\"a\":1,\"b\":[2]}");`,
+			[]json.RawMessage{
+				[]byte(`{"a":1,"b":[2]}`),
+			},
+		},
+		{
+			`[0] JSON.parse('{"a": \'b\'}') [1]`,
+			[]json.RawMessage{
+				[]byte(`[0]`),
+				[]byte(`{"a":"b"}`),
+				[]byte(`[1]`),
+			},
+		},
+		{
+			"var x = {data: JSON.parse ( `[1, 2]` )}",
+			[]json.RawMessage{
+				[]byte(`[1,2]`),
+			},
+		},
+		{
+			// Calls within the string are unwrapped too
+			`JSON.parse("{\"inner\": JSON.parse(\"[\\\"x\\\"]\")}")`,
+			[]json.RawMessage{
+				[]byte(`["x"]`),
+			},
+		},
+		{
+			`JSON.parse(data) {"a": 1}`,
+			[]json.RawMessage{
+				[]byte(`{"a":1}`),
+			},
+		},
+		{
+			"JSON.parse(`[${a}]`)",
+			nil,
+		},
+		{
+			`JSON.parse("[1]`,
+			[]json.RawMessage{
+				[]byte(`[1]`),
+			},
+		},
+		{
+			`JSON.pars`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, oneByte := range []bool{false, true} {
+			var r io.Reader = strings.NewReader(tt.input)
+			if oneByte {
+				r = iotest.OneByteReader(r)
+			}
+
+			var got []json.RawMessage
+			err := ReaderWithOptions(context.Background(), r, ExtractOptions{UnwrapJSONParse: true}, func(m Match) error {
+				got = append(got, m.Data)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReaderWithOptions(%q) = %v, want %v", tt.input, convert(got), convert(tt.want))
+			}
+		}
+	}
+}
+
+func TestReaderUnwrapJSONParseMatch(t *testing.T) {
+	var input = "[0]\nx = JSON.parse('{\"a\": [1]}'); [2] [3]"
+
+	var got []Match
+	err := ReaderWithOptions(context.Background(), strings.NewReader(input), ExtractOptions{UnwrapJSONParse: true}, func(m Match) error {
+		got = append(got, m)
+		if len(got) == 3 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("got %d matches, want 3", len(got))
+	}
+
+	var m = got[1]
+	if string(m.Data) != `{"a":[1]}` || string(m.Raw) != `{"a": [1]}` {
+		t.Errorf("got match %s with raw %s", m.Data, m.Raw)
+	}
+	if m.StartOffset != 19 || m.EndOffset != 31 || m.Line != 2 || m.Column != 16 {
+		t.Errorf("got position %d-%d (%d:%d), want 19-31 (2:16)", m.StartOffset, m.EndOffset, m.Line, m.Column)
+	}
+	if string(got[2].Data) != `[2]` {
+		t.Errorf("got %s after the unwrapped object, want [2]", got[2].Data)
+	}
+}
@@ -0,0 +1,135 @@
+package jsonextract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// schemaPrefixes are removed from @type values before comparing them, e.g. https://schema.org/Product is the same as Product
+var schemaPrefixes = []string{"https://schema.org/", "http://schema.org/", "schema:"}
+
+// LinkedData extracts the JSON-LD items of an HTML page, which are found in <script type="application/ld+json"> elements, and passes them to callback.
+// Arrays of items and the items in @graph arrays are flattened, which means that callback receives each item on its own.
+// The position of each match is the position of the item in the page. Scripts whose content is not a single valid value are skipped.
+//
+// If types is not empty, only items with one of these types in their @type value are passed to callback.
+// Types can be written with or without the schema.org prefix, e.g. "Product" also matches "https://schema.org/Product".
+//
+// Returning ErrStop from callback stops extraction without error. Other errors are returned.
+func LinkedData(ctx context.Context, r io.Reader, types []string, callback MatchCallback) (err error) {
+	var (
+		// content is the content of the script the current matches are in, contentOffset its offset in the page
+		content       []byte
+		contentOffset int
+	)
+	onScript := func(text []byte, offset int) {
+		content, contentOffset = text, offset
+	}
+
+	return readHTML(ctx, r, ExtractOptions{}, onScript, func(m Match) error {
+		// Other matches are parts of a script that is not valid JSON, e.g. an object in it that is valid on its own
+		if !isLinkedDataType(m.Element.Type) ||
+			!isWhole(content, int(m.StartOffset)-contentOffset, int(m.EndOffset)-contentOffset) {
+			return nil
+		}
+
+		return flattenLinkedData(m, 0, m.Data, func(item Match, itemTypes []string) error {
+			if len(types) > 0 && !containsType(itemTypes, types) {
+				return nil
+			}
+			return callback(item)
+		})
+	})
+}
+
+// isWhole returns whether content[start:end] is all of content apart from surrounding whitespace
+func isWhole(content []byte, start, end int) bool {
+	return start >= 0 && end <= len(content) &&
+		len(bytes.TrimSpace(content[:start])) == 0 && len(bytes.TrimSpace(content[end:])) == 0
+}
+
+// flattenLinkedData calls fn for every JSON-LD item in b, which is located at offset off in m.Data, together with the @type values of the item
+func flattenLinkedData(m Match, off int, b []byte, fn func(item Match, types []string) error) error {
+	switch b[0] {
+	case '[':
+		return walkJSON(b, func(_ string, value []byte, valueOff int) error {
+			return flattenLinkedData(m, off+valueOff, value, fn)
+		})
+	case '{':
+		var (
+			graph    []byte
+			graphOff int
+			types    []string
+		)
+		err := walkJSON(b, func(key string, value []byte, valueOff int) error {
+			switch key {
+			case "@graph":
+				graph, graphOff = value, valueOff
+			case "@type":
+				types = linkedDataTypes(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// An object with a graph is only a container for the items in it
+		if graph != nil {
+			return flattenLinkedData(m, off+graphOff, graph, fn)
+		}
+
+		return fn(m.sub(off, b), types)
+	}
+
+	return nil
+}
+
+// linkedDataTypes returns the types in the @type value b, which can be a string or an array of strings
+func linkedDataTypes(b []byte) (types []string) {
+	if json.Unmarshal(b, &types) == nil {
+		return types
+	}
+
+	var t string
+	if json.Unmarshal(b, &t) == nil {
+		return []string{t}
+	}
+
+	return nil
+}
+
+// containsType returns whether one of the types of an item is in want
+func containsType(types, want []string) bool {
+	for _, t := range types {
+		t = trimSchemaPrefix(t)
+
+		for _, w := range want {
+			if t == trimSchemaPrefix(w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// trimSchemaPrefix removes the schema.org prefix from typ, e.g. https://schema.org/Product becomes Product
+func trimSchemaPrefix(typ string) string {
+	for _, prefix := range schemaPrefixes {
+		if strings.HasPrefix(typ, prefix) {
+			return typ[len(prefix):]
+		}
+	}
+	return typ
+}
+
+// isLinkedDataType returns whether a script with the type attribute typ contains JSON-LD
+func isLinkedDataType(typ string) bool {
+	if i := strings.IndexByte(typ, ';'); i >= 0 {
+		typ = typ[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(typ), "application/ld+json")
+}
@@ -0,0 +1,103 @@
+package jsonextract
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const linkedDataPage = `<html>
+<head>
+	<script>var notLinkedData = {"@type": "Person", "name": "X"};</script>
+	<script type="application/ld+json">
+		{"@context": "https://schema.org", "@type": "Organization", "name": "Org"}
+	</script>
+	<script type="Application/LD+JSON; charset=utf-8">
+	{
+		"@context": "https://schema.org",
+		"@graph": [
+			{"@type": "WebPage", "name": "Page"},
+			{"@type": ["Product", "Thing"], "name": "Shoe", "offers": {"@type": "Offer", "price": 5}}
+		]
+	}
+	</script>
+	<script type="application/ld+json">
+		{"@type": "Product", "name": "Broken", "offers": {"@type": "Offer", "price": "5"}, bad}
+	</script>
+	<script type="application/ld+json">[{"@type": "https://schema.org/Person", "name": "A"}, {"name": "Untyped"}]</script>
+</head>
+</html>`
+
+func TestLinkedData(t *testing.T) {
+	tests := []struct {
+		types []string
+		want  []string
+	}{
+		{
+			nil,
+			[]string{
+				`{"@context":"https://schema.org","@type":"Organization","name":"Org"}`,
+				`{"@type":"WebPage","name":"Page"}`,
+				`{"@type":["Product","Thing"],"name":"Shoe","offers":{"@type":"Offer","price":5}}`,
+				`{"@type":"https://schema.org/Person","name":"A"}`,
+				`{"name":"Untyped"}`,
+			},
+		},
+		{
+			[]string{"Thing", "schema:Person"},
+			[]string{
+				`{"@type":["Product","Thing"],"name":"Shoe","offers":{"@type":"Offer","price":5}}`,
+				`{"@type":"https://schema.org/Person","name":"A"}`,
+			},
+		},
+		{
+			// Nested items are not flattened, not even if the item around them is not valid
+			[]string{"Offer"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.types, ","), func(t *testing.T) {
+			var got []string
+			err := LinkedData(context.Background(), strings.NewReader(linkedDataPage), tt.types, func(m Match) error {
+				got = append(got, string(m.Data))
+
+				if source := linkedDataPage[m.StartOffset:m.EndOffset]; source != string(m.Raw) {
+					t.Errorf("offsets of %s point to %q, but Raw is %q", m.Data, source, m.Raw)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LinkedData() returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkedDataStop(t *testing.T) {
+	var calls int
+	err := LinkedData(context.Background(), strings.NewReader(linkedDataPage), nil, func(m Match) error {
+		calls++
+		return ErrStop
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback was called %d times after returning ErrStop, want 1", calls)
+	}
+
+	var errTest = errors.New("test")
+	err = LinkedData(context.Background(), strings.NewReader(linkedDataPage), nil, func(m Match) error {
+		return errTest
+	})
+	if err != errTest {
+		t.Errorf("LinkedData() returned %v, want %v", err, errTest)
+	}
+}
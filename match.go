@@ -0,0 +1,164 @@
+package jsonextract
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Match describes a JSON value that was extracted from the input, including where it was found.
+type Match struct {
+	// Data contains the value converted to JSON
+	Data []byte
+
+	// Raw contains the original bytes of the value as they were found in the input,
+	// e.g. the JavaScript object before it was converted to JSON
+	Raw []byte
+
+	// StartOffset is the byte offset of the first byte of the value in the input,
+	// EndOffset is the offset of the first byte after it
+	StartOffset, EndOffset int64
+
+	// Line and Column describe where the value starts in the input.
+	// Both start at 1, the column is counted in bytes
+	Line, Column int
+
+	// AssignedTo is the variable or property the value is assigned to in the input, e.g. "ytInitialData" for
+	// var ytInitialData = {...} or "window.__APOLLO_STATE__" for window["__APOLLO_STATE__"] = {...}.
+	// It is only set if ExtractOptions.ReportAssignments is set, values within other values never have it
+	AssignedTo string
+
+	// Element is the HTML element that contains the value. It is only set by HTMLReader
+	Element *HTMLElement
+
+	// Path is the location of the value within the top-level object or array it was found in, e.g. "$.contents[3].videoRenderer".
+	// It uses the syntax of ObjectOption.Path. Depth is the number of keys and indices in it, it is 0 for top-level values.
+	// ParentKey is the last key in Path, e.g. "videoRenderer", it is empty for top-level values and values directly within top-level arrays.
+	// These fields are only set by Objects
+	Path      string
+	Depth     int
+	ParentKey string
+
+	// brackets maps the brackets in Data back to their position in Raw
+	brackets *sourceMap
+}
+
+// MatchCallback is the callback function passed to ReaderMatches and ObjectOptions.
+// It works just like JSONCallback, but also receives the position of each value.
+type MatchCallback func(m Match) error
+
+// sub returns the match for b, which must be a value found at offset off in m.Data.
+// If the position of b cannot be determined, the position and Raw of m are kept
+func (m Match) sub(off int, b []byte) Match {
+	var s = m
+	s.Data = b
+
+	// Only the outermost value is assigned to something
+	if off != 0 || len(b) != len(m.Data) {
+		s.AssignedTo = ""
+	}
+
+	if m.brackets == nil {
+		return s
+	}
+
+	start, ok := m.brackets.lookup(off)
+	if !ok {
+		return s
+	}
+	end, ok := m.brackets.lookup(off + len(b) - 1)
+	if !ok {
+		return s
+	}
+
+	var pos = position{offset: m.StartOffset, line: m.Line, column: m.Column}
+	pos.advance(m.Raw[:start])
+
+	s.Raw = m.Raw[start : end+1]
+	s.StartOffset = pos.offset
+	s.EndOffset = m.StartOffset + int64(end) + 1
+	s.Line, s.Column = pos.line, pos.column
+
+	return s
+}
+
+// moveTo sets the position of m to the n bytes at pos. It is used for values that were found in decoded data,
+// e.g. within a string literal, where their exact position in the input is not known
+func (m *Match) moveTo(pos position, n int) {
+	m.StartOffset, m.EndOffset = pos.offset, pos.offset+int64(n)
+	m.Line, m.Column = pos.line, pos.column
+	m.brackets = nil
+}
+
+// shift moves m, which was found in data that starts at pos in the input, to its position in the input
+func (m *Match) shift(pos position) {
+	m.StartOffset += pos.offset
+	m.EndOffset += pos.offset
+	if m.Line == 1 {
+		m.Column += pos.column - 1
+	}
+	m.Line += pos.line - 1
+}
+
+// position tracks the offset, line and column while reading input
+type position struct {
+	offset       int64
+	line, column int
+}
+
+func newPosition() position {
+	return position{line: 1, column: 1}
+}
+
+// advance moves the position behind b
+func (p *position) advance(b []byte) {
+	p.offset += int64(len(b))
+
+	if nl := bytes.LastIndexByte(b, '\n'); nl >= 0 {
+		p.line += bytes.Count(b, []byte{'\n'})
+		p.column = len(b) - nl
+	} else {
+		p.column += len(b)
+	}
+}
+
+// advanceByte moves the position behind c
+func (p *position) advanceByte(c byte) {
+	p.advanceRune(rune(c), 1)
+}
+
+// advanceRune moves the position behind r, which is size bytes long
+func (p *position) advanceRune(r rune, size int) {
+	p.offset += int64(size)
+	if r == '\n' {
+		p.line++
+		p.column = 1
+	} else {
+		p.column += size
+	}
+}
+
+// sourceMap maps the offsets of brackets in the converted output back to the offsets in the input they were read from
+type sourceMap struct {
+	out []int
+	in  []int
+}
+
+func (s *sourceMap) add(out, in int) {
+	s.out = append(s.out, out)
+	s.in = append(s.in, in)
+}
+
+// truncate removes the brackets at output offset out and after it
+func (s *sourceMap) truncate(out int) {
+	i := sort.SearchInts(s.out, out)
+	s.out, s.in = s.out[:i], s.in[:i]
+}
+
+// lookup returns the input offset of the bracket at the output offset out
+func (s *sourceMap) lookup(out int) (in int, ok bool) {
+	i := sort.SearchInts(s.out, out)
+	if i == len(s.out) || s.out[i] != out {
+		return 0, false
+	}
+	return s.in[i], true
+}
@@ -0,0 +1,355 @@
+package jsonextract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stdhtml "html"
+	"io"
+	"strings"
+
+	"github.com/tdewolff/parse/v2"
+	"github.com/tdewolff/parse/v2/html"
+)
+
+// valueAttributes are the attributes that contain the value of a property on certain elements. The value of a property on other elements is their text
+var valueAttributes = map[string]string{
+	"meta":   "content",
+	"audio":  "src",
+	"embed":  "src",
+	"iframe": "src",
+	"img":    "src",
+	"source": "src",
+	"track":  "src",
+	"video":  "src",
+	"a":      "href",
+	"area":   "href",
+	"link":   "href",
+	"object": "data",
+	"data":   "value",
+	"meter":  "value",
+	"time":   "datetime",
+}
+
+// microdataItem is an element with an itemscope attribute
+type microdataItem struct {
+	types []string
+	id    string
+
+	// props are the properties of the item in the order their elements start
+	props []*microdataProperty
+
+	// values are the properties the item is the value of. They are empty if it is a top-level item
+	values []*microdataProperty
+
+	// element describes the element of the item, start and end are its offsets in the page.
+	// end is -1 as long as the element wasn't closed
+	element    *HTMLElement
+	start, end int
+}
+
+// microdataProperty is the value of an itemprop attribute
+type microdataProperty struct {
+	name string
+
+	// value is the JSON value of the property. It is set once the element of the property was read completely
+	value []byte
+}
+
+// microdataElement is an element that was opened, but not yet closed
+type microdataElement struct {
+	tag string
+
+	// item is the item started by the element, or nil if it doesn't have an itemscope attribute
+	item *microdataItem
+
+	// props are the properties whose value is the text of the element
+	props []*microdataProperty
+	text  *bytes.Buffer
+}
+
+// Microdata extracts the schema.org microdata items of an HTML page, which are elements with an itemscope attribute, and passes them to callback.
+// Each item is converted to a JSON object with the itemtype attribute as "@type", the itemid attribute as "@id" and its properties, e.g.
+// <div itemscope itemtype="https://schema.org/Product"><span itemprop="name">Shoe</span></div> results in {"@type":"https://schema.org/Product","name":"Shoe"}.
+//
+// Items are passed to callback in the order they start in the page. Items that are the value of a property of another item are not passed to
+// callback on their own, they are part of the object of the item that contains them. Properties with several values are converted to arrays.
+// The value of a property is the text of its element or an attribute for some elements, e.g. the content attribute of <meta> and the href
+// attribute of <a>. URLs are returned as they are written in the page. Properties that refer to other elements using itemref are not supported.
+//
+// The position of each match is the position of the element of the item in the page, Raw contains its HTML and Element describes it.
+//
+// If types is not empty, only items with one of these types in their itemtype attribute are passed to callback.
+// Types can be written with or without the schema.org prefix, e.g. "Product" also matches "https://schema.org/Product".
+//
+// Returning ErrStop from callback stops extraction without error. Other errors are returned.
+func Microdata(ctx context.Context, r io.Reader, types []string, callback MatchCallback) (err error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	input := parse.NewInputBytes(page)
+	defer input.Restore()
+
+	var (
+		lex = html.NewLexer(input)
+
+		// pos is the position of page[pos.offset:] in the input
+		pos = newPosition()
+
+		// element is the element whose start tag is currently being read, elementStart is its offset
+		element      *HTMLElement
+		elementStart int
+		// attributes contains the attributes of element that matter for microdata
+		attributes = make(map[string]string)
+		// open contains the elements that were opened, but not yet closed
+		open []*microdataElement
+		// items contains the top-level items that were not yet passed to callback in the order they start
+		items []*microdataItem
+	)
+
+	// emit passes the items at the start of items that were closed to callback
+	emit := func() (stopped bool, err error) {
+		for len(items) > 0 && items[0].end >= 0 {
+			var item = items[0]
+			items = items[1:]
+
+			if len(types) > 0 && !containsType(item.types, types) {
+				continue
+			}
+
+			pos.advance(page[int(pos.offset):item.start])
+			err = callback(Match{
+				Data:        item.json(),
+				Raw:         page[item.start:item.end],
+				StartOffset: int64(item.start),
+				EndOffset:   int64(item.end),
+				Line:        pos.line,
+				Column:      pos.column,
+				Element:     item.element,
+			})
+			if err != nil {
+				if err == ErrStop {
+					return true, nil
+				}
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// closeElement sets the values of the properties of e, which ends at offset end
+	closeElement := func(e *microdataElement, end int) {
+		if e.text != nil {
+			value := marshalText(strings.TrimSpace(stdhtml.UnescapeString(e.text.String())))
+			for _, prop := range e.props {
+				prop.value = value
+			}
+		}
+
+		if e.item != nil {
+			e.item.end = end
+			value := e.item.json()
+			for _, prop := range e.item.values {
+				prop.value = value
+			}
+		}
+	}
+
+	// openElement handles the start tag of element once all of its attributes are known
+	openElement := func(void bool) {
+		// Properties belong to the innermost item the element is in
+		var scope *microdataItem
+		for i := len(open) - 1; i >= 0 && scope == nil; i-- {
+			scope = open[i].item
+		}
+
+		var (
+			e     = &microdataElement{tag: element.Tag}
+			props []*microdataProperty
+		)
+		if names, ok := attributes["itemprop"]; ok && scope != nil {
+			for _, name := range strings.Fields(names) {
+				prop := &microdataProperty{name: name}
+				scope.props = append(scope.props, prop)
+				props = append(props, prop)
+			}
+		}
+
+		if _, ok := attributes["itemscope"]; ok {
+			e.item = &microdataItem{
+				types:   strings.Fields(attributes["itemtype"]),
+				id:      attributes["itemid"],
+				values:  props,
+				element: element,
+				start:   elementStart,
+				end:     -1,
+			}
+			// Items that are not the value of a property are top-level items, even within other items
+			if len(props) == 0 {
+				items = append(items, e.item)
+			}
+		} else if len(props) > 0 {
+			// The text of a <time> element is its value if it doesn't have a datetime attribute
+			attr, ok := valueAttributes[element.Tag]
+			attrValue, hasValue := attributes[attr]
+
+			if ok && (hasValue || element.Tag != "time") {
+				value := marshalText(attrValue)
+				for _, prop := range props {
+					prop.value = value
+				}
+			} else {
+				e.props, e.text = props, new(bytes.Buffer)
+			}
+		}
+
+		if void || voidElements[element.Tag] {
+			closeElement(e, input.Offset())
+			return
+		}
+		open = append(open, e)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		tt, data := lex.Next()
+		switch tt {
+		case html.ErrorToken:
+			if lex.Err() != io.EOF {
+				return lex.Err()
+			}
+
+			// Elements that are never closed end with the page
+			for i := len(open) - 1; i >= 0; i-- {
+				closeElement(open[i], len(page))
+			}
+			_, err = emit()
+			return err
+		case html.StartTagToken:
+			element = &HTMLElement{Tag: string(lex.Text())}
+			elementStart = input.Offset() - len(data)
+			for key := range attributes {
+				delete(attributes, key)
+			}
+		case html.AttributeToken:
+			if element == nil {
+				continue
+			}
+
+			switch key := string(lex.AttrKey()); key {
+			case "id":
+				element.ID = attributeValue(lex.AttrVal())
+			case "type":
+				element.Type = attributeValue(lex.AttrVal())
+			case "itemscope", "itemprop", "itemtype", "itemid", "content", "src", "href", "data", "value", "datetime":
+				attributes[key] = attributeValue(lex.AttrVal())
+			}
+		case html.StartTagCloseToken, html.StartTagVoidToken:
+			if element == nil {
+				continue
+			}
+			openElement(tt == html.StartTagVoidToken)
+			element = nil
+		case html.EndTagToken:
+			// Close the element and all elements within it that were never closed
+			var tag = string(lex.Text())
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i].tag != tag {
+					continue
+				}
+
+				for j := len(open) - 1; j >= i; j-- {
+					closeElement(open[j], input.Offset())
+				}
+				open = open[:i]
+				break
+			}
+			element = nil
+
+			stopped, serr := emit()
+			if serr != nil || stopped {
+				return serr
+			}
+		case html.TextToken:
+			// The text of scripts and styles is not part of the text of an element
+			if len(open) > 0 && (open[len(open)-1].tag == "script" || open[len(open)-1].tag == "style") {
+				continue
+			}
+
+			for _, e := range open {
+				if e.text != nil {
+					e.text.Write(data)
+				}
+			}
+		default:
+			element = nil
+		}
+	}
+}
+
+// json returns the JSON object of item
+func (item *microdataItem) json() []byte {
+	var (
+		buf bytes.Buffer
+
+		// names contains the names of the properties in the order they first appear, values their values
+		names  []string
+		values = make(map[string][][]byte)
+	)
+
+	write := func(key string, value []byte) {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(marshalText(key))
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('{')
+
+	switch len(item.types) {
+	case 0:
+	case 1:
+		write("@type", marshalText(item.types[0]))
+	default:
+		write("@type", marshalText(item.types))
+	}
+	if item.id != "" {
+		write("@id", marshalText(item.id))
+	}
+
+	for _, prop := range item.props {
+		if _, ok := values[prop.name]; !ok {
+			names = append(names, prop.name)
+		}
+		values[prop.name] = append(values[prop.name], prop.value)
+	}
+
+	for _, name := range names {
+		if v := values[name]; len(v) == 1 {
+			write(name, v[0])
+		} else {
+			write(name, append(append([]byte{'['}, bytes.Join(v, []byte{','})...), ']'))
+		}
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// marshalText returns the JSON representation of a string or a slice of strings. Unlike json.Marshal, it doesn't escape HTML characters like '&'
+func marshalText(v interface{}) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+}
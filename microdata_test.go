@@ -0,0 +1,118 @@
+package jsonextract
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const microdataPage = `<html>
+<body>
+	<div itemscope itemtype="https://schema.org/Product" itemid="#shoe" id="product">
+		<h1 itemprop="name">Shoe &amp; Sock</h1>
+		<img itemprop="image" src="/shoe.jpg" alt="">
+		<img itemprop="image" src="/shoe-2.jpg" alt="">
+		<p>Color: <span itemprop="color description">Red <b>and</b> blue</span></p>
+		<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+			<meta itemprop="priceCurrency" content="EUR">
+			<span itemprop="price">5</span>
+			<link itemprop="availability" href="https://schema.org/InStock">
+			<time itemprop="validFrom" datetime="2024-01-01">January</time>
+		</div>
+		<div itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">Nested, but not a property</span>
+		</div>
+	</div>
+	<span itemprop="name">Not in an item</span>
+	<article itemscope itemtype="schema:Article schema:Thing">
+		<time itemprop="datePublished">2024-02-02</time>
+		<script>var s = "not text";</script>
+	<section itemscope>
+		<span itemprop="name">Never closed</span>
+</body>
+</html>`
+
+func TestMicrodata(t *testing.T) {
+	tests := []struct {
+		types []string
+		want  []string
+	}{
+		{
+			nil,
+			[]string{
+				`{"@type":"https://schema.org/Product","@id":"#shoe","name":"Shoe & Sock","image":["/shoe.jpg","/shoe-2.jpg"],"color":"Red and blue","description":"Red and blue","offers":{"@type":"https://schema.org/Offer","priceCurrency":"EUR","price":"5","availability":"https://schema.org/InStock","validFrom":"2024-01-01"}}`,
+				`{"@type":"https://schema.org/Person","name":"Nested, but not a property"}`,
+				`{"@type":["schema:Article","schema:Thing"],"datePublished":"2024-02-02"}`,
+				`{"name":"Never closed"}`,
+			},
+		},
+		{
+			[]string{"Thing", "schema:Person"},
+			[]string{
+				`{"@type":"https://schema.org/Person","name":"Nested, but not a property"}`,
+				`{"@type":["schema:Article","schema:Thing"],"datePublished":"2024-02-02"}`,
+			},
+		},
+		{
+			// Items that are the value of a property are only part of the item that contains them
+			[]string{"Offer"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.types, ","), func(t *testing.T) {
+			var got []string
+			err := Microdata(context.Background(), strings.NewReader(microdataPage), tt.types, func(m Match) error {
+				got = append(got, string(m.Data))
+
+				if source := microdataPage[m.StartOffset:m.EndOffset]; source != string(m.Raw) {
+					t.Errorf("offsets of %s point to %q, but Raw is %q", m.Data, source, m.Raw)
+				}
+				if !strings.HasPrefix(string(m.Raw), "<"+m.Element.Tag) {
+					t.Errorf("Raw of %s is %q, which doesn't start with the element %q", m.Data, m.Raw, m.Element.Tag)
+				}
+
+				before := microdataPage[:m.StartOffset]
+				line, column := strings.Count(before, "\n")+1, len(before)-strings.LastIndexByte(before, '\n')
+				if m.Line != line || m.Column != column {
+					t.Errorf("%s is at %d:%d, want %d:%d", m.Data, m.Line, m.Column, line, column)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Microdata() returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMicrodataStop(t *testing.T) {
+	var calls int
+	err := Microdata(context.Background(), strings.NewReader(microdataPage), nil, func(m Match) error {
+		calls++
+		if m.Element.ID != "product" {
+			t.Errorf("first item has the element %+v, want the one with the id %q", m.Element, "product")
+		}
+		return ErrStop
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback was called %d times after returning ErrStop, want 1", calls)
+	}
+
+	var errTest = errors.New("test")
+	err = Microdata(context.Background(), strings.NewReader(microdataPage), nil, func(m Match) error {
+		return errTest
+	})
+	if err != errTest {
+		t.Errorf("Microdata() returned %v, want %v", err, errTest)
+	}
+}
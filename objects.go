@@ -1,8 +1,11 @@
 package jsonextract
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 )
@@ -33,22 +36,118 @@ func Unmarshal(pointer interface{}, verify func() bool) JSONCallback {
 type ObjectOption struct {
 	// Keys defines a filter for objects. Only objects where these keys are present will be passed to Callback.
 	// If this is not set, all objects will be passed to the callback.
+	//
+	// Keys of nested objects are separated by dots, e.g. "author.name" requires an "author" object with a "name" key.
+	// Dots and backslashes that are part of a key can be escaped with a backslash, e.g. "a\\.b" for the key "a.b".
+	// A key that is present in the object exactly as written is always accepted, which means that unescaped keys with dots also work
 	Keys []string
 
+	// AssignedTo restricts this option to the object that is assigned to this variable or property,
+	// e.g. "ytInitialData" for var ytInitialData = {...}. Names are written like in ObjectByName.
+	// Objects within that object don't match, they are not assigned to anything themselves
+	AssignedTo string
+
+	// Path restricts this option to objects at a certain location within each top-level object or array. Keys are separated by dots
+	// and array indices are written in brackets, e.g. "contents[0].videoRenderer" or "$.contents[0].videoRenderer".
+	// "*" matches any single key or index, ".." matches any number of keys and indices, e.g. "contents..videoRenderer" matches
+	// videoRenderer objects at any depth within contents and "..videoRenderer" matches them anywhere.
+	// Keys that contain dots or brackets can be quoted in brackets, e.g. ["a.b"]. An empty path matches objects at any location
+	Path string
+
+	// Values restricts this option to objects where the values of these keys are accepted by the predicates, e.g.
+	// map[string]ValuePredicate{"type": Equals("video"), "price": GreaterThan(0)}. The keys must be present in the object
+	Values map[string]ValuePredicate
+
+	// Filter can be set to decide whether an object that passed all other checks is accepted by this option.
+	// It receives the keys and values of the object, the values must not be modified
+	Filter func(m map[string]json.RawMessage) bool
+
 	// Callback receives JSON bytes for all objects that have all keys defined by Keys.
 	// Returning ErrStop will stop extraction without error. Other errors will be returned.
 	Callback JSONCallback
 
+	// MatchCallback can be set instead of Callback if the position of the object in the input or its path is needed.
+	// If both are set, only MatchCallback is called.
+	MatchCallback MatchCallback
+
 	// Required sets whether ErrCallbackNeverCalled should be returned if the callback function for this ObjectOption is not called
 	Required bool
+
+	// Continue sets whether the options after this one are also checked for objects that matched this option.
+	// By default, only the first matching option is called. Setting it for all options calls every option that matches,
+	// which is useful if independent consumers like logging and storage want to receive the same objects
+	Continue bool
+}
+
+// call calls the callback that was set for this option with b, which was found at offset off and path in parent
+func (s *ObjectOption) call(parent Match, off int, path []pathElement, b []byte) error {
+	if s.MatchCallback != nil {
+		var m = parent.sub(off, b)
+		m.Path, m.Depth = formatPath(path), len(path)
+		m.ParentKey = lastKey(path)
+
+		return s.MatchCallback(m)
+	}
+	return s.Callback(b)
 }
 
-func (s *ObjectOption) match(m map[string]rawMessageNoCopy) bool {
-	for _, k := range s.Keys {
-		if _, ok := m[k]; !ok {
+// optionFilters contains the parsed Path and Keys of an ObjectOption
+type optionFilters struct {
+	selectors []pathSelector
+
+	// keys contains the parts of each key in Keys, e.g. ["author", "name"] for "author.name"
+	keys [][]string
+}
+
+// parseFilters parses the Path and Keys of this option
+func (s *ObjectOption) parseFilters() (f optionFilters, err error) {
+	if s.Path != "" {
+		f.selectors, err = parsePath(s.Path)
+		if err != nil {
+			return f, fmt.Errorf("invalid path %q: %w", s.Path, err)
+		}
+	}
+
+	f.keys = make([][]string, len(s.Keys))
+	for i, k := range s.Keys {
+		f.keys[i] = splitKeyPath(k)
+	}
+
+	return f, nil
+}
+
+// match returns whether the object m, which is assigned to assignedTo and located at path, is accepted by this option.
+// f must have been returned by parseFilters
+func (s *ObjectOption) match(m map[string]rawMessageNoCopy, assignedTo string, path []pathElement, f optionFilters) bool {
+	if s.Path != "" && !matchPath(f.selectors, path) {
+		return false
+	}
+
+	if s.AssignedTo != "" && (assignedTo == "" || trimGlobalObject(assignedTo) != trimGlobalObject(s.AssignedTo)) {
+		return false
+	}
+
+	for i, k := range s.Keys {
+		if _, ok := m[k]; !ok && !hasKeyPath(m, f.keys[i]) {
+			return false
+		}
+	}
+
+	for k, accept := range s.Values {
+		value, ok := m[k]
+		if !ok || !accept(json.RawMessage(value)) {
 			return false
 		}
 	}
+
+	if s.Filter != nil {
+		var values = make(map[string]json.RawMessage, len(m))
+		for k, v := range m {
+			values[k] = json.RawMessage(v)
+		}
+		return s.Filter(values)
+	}
+
 	return true
 }
 
@@ -63,52 +162,90 @@ var ErrCallbackNeverCalled = errors.New("callback never called")
 //
 // If multiple options would match, only the first one will be processed. This allows you to cascade options
 // to first extract objects with the most keys, then those with less (which is useful if there are overlapping keys).
+// Options that have Continue set don't stop the search, which means that the next matching option is also processed.
 //
 // If a required option is not matched, ErrCallbackNeverCalled will be returned.
 //
 // Arrays/Slices will not cause a callback as they don't have keys, but objects in them will be matched.
 func Objects(r io.Reader, o []ObjectOption) (err error) {
+	return ObjectsContext(context.Background(), r, o)
+}
+
+// ObjectsContext works like Objects, but stops processing when ctx is done.
+// In that case, ctx.Err() is returned.
+func ObjectsContext(ctx context.Context, r io.Reader, o []ObjectOption) (err error) {
+	return ObjectsWithOptions(ctx, r, ExtractOptions{}, o)
+}
+
+// ObjectsWithOptions works like ObjectsContext, but converts objects as defined by opts.
+func ObjectsWithOptions(ctx context.Context, r io.Reader, opts ExtractOptions, o []ObjectOption) (err error) {
+	// Options can only match assignments if they are known
+	for _, opt := range o {
+		if opt.AssignedTo != "" {
+			opts.ReportAssignments = true
+			break
+		}
+	}
+
+	var filters = make([]optionFilters, len(o))
+	for i := range o {
+		filters[i], err = o[i].parseFilters()
+		if err != nil {
+			return err
+		}
+	}
 
 	var (
 		satisfiedCallbacks = make(map[int]bool)
 		satisfiedCount     int
 
-		keyFunc func(b []byte) error
+		// current is the top-level match that is currently being processed
+		current Match
+
+		keyFunc func(b []byte, off int, path []pathElement) error
 	)
 
-	keyFunc = func(b []byte) (err error) {
+	// keyFunc walks through b, which is located at offset off and path in current.Data
+	keyFunc = func(b []byte, off int, path []pathElement) (err error) {
 		if b[0] == '[' {
-			// Decode the array
-			var arr []rawMessageNoCopy
-
-			err = json.Unmarshal(b, &arr)
+			// Now walk through all elements and check them using this same function
+			var index int
+			err = walkJSON(b, func(_ string, elem []byte, elemOff int) error {
+				index++
+				return keyFunc(elem, off+elemOff, append(path, pathElement{index: index - 1}))
+			})
 			if err != nil {
 				return
 			}
-
-			// Now walk through all elements and check them using this same function
-			for _, elem := range arr {
-				err = keyFunc(elem)
-				if err != nil {
-					return
-				}
-			}
 		} else if b[0] == '{' {
-			var m map[string]rawMessageNoCopy
-
-			err = json.Unmarshal(b, &m)
+			var (
+				m       = make(map[string]rawMessageNoCopy)
+				offsets = make(map[string]int)
+			)
+
+			err = walkJSON(b, func(key string, value []byte, valueOff int) error {
+				m[key] = value
+				offsets[key] = valueOff
+				return nil
+			})
 			if err != nil {
 				return
 			}
 
+			// Only the top-level object is assigned to something
+			var assignedTo string
+			if off == 0 {
+				assignedTo = current.AssignedTo
+			}
+
 			// Match the first option that is good for this struct
 			for i, opt := range o {
 				if satisfiedCallbacks[i] {
 					continue
 				}
 
-				if opt.match(m) {
-					oerr := opt.Callback(b)
+				if opt.match(m, assignedTo, path, filters[i]) {
+					oerr := opt.call(current, off, path, b)
 					if oerr == ErrStop {
 						// Mark this callback function as done
 						satisfiedCallbacks[i] = true
@@ -125,8 +262,10 @@ func Objects(r io.Reader, o []ObjectOption) (err error) {
 						return oerr
 					}
 
-					// Since only the first option that matches should be called
-					break
+					// Since only the first option that matches should be called, unless it wants to share the object
+					if !opt.Continue {
+						break
+					}
 				}
 			}
 
@@ -140,7 +279,7 @@ func Objects(r io.Reader, o []ObjectOption) (err error) {
 			sort.Strings(keys)
 
 			for _, key := range keys {
-				err = keyFunc(m[key])
+				err = keyFunc(m[key], off+offsets[key], append(path, pathElement{key: key, index: -1}))
 				if err != nil {
 					return
 				}
@@ -150,7 +289,10 @@ func Objects(r io.Reader, o []ObjectOption) (err error) {
 		return nil
 	}
 
-	err = Reader(r, keyFunc)
+	err = ReaderWithOptions(ctx, r, opts, func(m Match) error {
+		current = m
+		return keyFunc(m.Data, 0, nil)
+	})
 
 	// Only check required callbacks if there are no other errors
 	if err == nil && satisfiedCount != len(o) {
@@ -168,19 +310,149 @@ func Objects(r io.Reader, o []ObjectOption) (err error) {
 	return
 }
 
+// splitKeyPath splits key at unescaped dots and removes the escaping backslashes, e.g. "a.b\\.c" becomes ["a", "b.c"]
+func splitKeyPath(key string) (parts []string) {
+	var current []byte
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c == '\\' && i+1 < len(key) && (key[i+1] == '.' || key[i+1] == '\\'):
+			i++
+			current = append(current, key[i])
+		case c == '.':
+			parts = append(parts, string(current))
+			current = current[:0]
+		default:
+			current = append(current, c)
+		}
+	}
+
+	return append(parts, string(current))
+}
+
+// hasKeyPath returns whether m contains the nested keys parts, e.g. whether m["author"] is an object with a "name" key for ["author", "name"]
+func hasKeyPath(m map[string]rawMessageNoCopy, parts []string) bool {
+	value, ok := m[parts[0]]
+	if !ok {
+		return false
+	}
+
+	for _, part := range parts[1:] {
+		if value[0] != '{' {
+			return false
+		}
+
+		var found []byte
+		err := walkJSON(value, func(key string, v []byte, _ int) error {
+			if key == part {
+				found = v
+				return ErrStop
+			}
+			return nil
+		})
+		if err != nil && err != ErrStop || found == nil {
+			return false
+		}
+		value = found
+	}
+
+	return true
+}
+
 // rawMessageNoCopy is like json.RawMessage, except that it doesn't make a full copy
 type rawMessageNoCopy []byte
 
-// compile error if we don't implement Unmarshaler
-var _ json.Unmarshaler = (*rawMessageNoCopy)(nil)
+// walkJSON calls fn for every value of the JSON object or array in b, which must be valid JSON.
+// off is the offset of the value in b. For arrays, key is always empty
+func walkJSON(b []byte, fn func(key string, value []byte, off int) error) (err error) {
+	var isObject = b[0] == '{'
+
+	for i := 1; i < len(b); {
+		i = skipJSONSpace(b, i)
+		if b[i] == ',' {
+			i = skipJSONSpace(b, i+1)
+		}
+		if b[i] == ']' || b[i] == '}' {
+			break
+		}
 
-// UnmarshalJSON sets *m to data, implements json.Unmarshaler
-func (m *rawMessageNoCopy) UnmarshalJSON(data []byte) error {
-	if m == nil {
-		return errors.New("rawMessageNoCopy: UnmarshalJSON on nil pointer")
+		var key string
+		if isObject {
+			end := skipJSONValue(b, i)
+			key, err = decodeJSONKey(b[i:end])
+			if err != nil {
+				return
+			}
+
+			// Skip the colon after the key
+			i = skipJSONSpace(b, skipJSONSpace(b, end)+1)
+		}
+
+		end := skipJSONValue(b, i)
+
+		err = fn(key, b[i:end], i)
+		if err != nil {
+			return
+		}
+
+		i = end
 	}
-	// this should copy the slice header, but not the underlying data
-	*m = data
 
 	return nil
 }
+
+// decodeJSONKey decodes the quoted JSON string key
+func decodeJSONKey(key []byte) (string, error) {
+	if bytes.IndexByte(key, '\\') < 0 {
+		return string(key[1 : len(key)-1]), nil
+	}
+
+	var s string
+	err := json.Unmarshal(key, &s)
+	return s, err
+}
+
+// skipJSONValue returns the index right after the JSON value starting at b[i]
+func skipJSONValue(b []byte, i int) int {
+	var depth int
+
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case '"':
+			// Skip to the closing quote, ignoring escaped ones
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth < 0 {
+				// End of the surrounding object
+				return i
+			}
+		case ',', ':', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				return i
+			}
+			continue
+		default:
+			continue
+		}
+
+		if depth == 0 {
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// skipJSONSpace returns the index of the first non-whitespace character at or after b[i]
+func skipJSONSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	return i
+}
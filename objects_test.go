@@ -2,6 +2,7 @@ package jsonextract
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
@@ -136,6 +137,48 @@ func TestObjectsFirstMatchingOptionOnly(t *testing.T) {
 	}
 }
 
+func TestObjectsContinue(t *testing.T) {
+	var data = `{key1: "a", a: {key1: "b", key2: 2}}`
+
+	var calls = map[int]int{0: 0, 1: 0, 2: 0, 3: 0}
+
+	var cb = func(i int) JSONCallback {
+		return func(b []byte) error {
+			calls[i]++
+			return nil
+		}
+	}
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Keys:     []string{"key1"},
+			Callback: cb(0),
+			Continue: true,
+		},
+		{
+			Keys:     []string{"key1", "key2"},
+			Callback: cb(1),
+			Continue: true,
+		},
+		{
+			// Options without Continue still end the search
+			Keys:     []string{"key1"},
+			Callback: cb(2),
+		},
+		{
+			Callback: cb(3),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = map[int]int{0: 2, 1: 1, 2: 2, 3: 0}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("Objects() called callbacks %v times, want %v", calls, want)
+	}
+}
+
 func TestObjectsCascade(t *testing.T) {
 	var data = `{key1: "a", a: {key1: "b", key2: 2}}`
 
@@ -167,6 +210,337 @@ func TestObjectsCascade(t *testing.T) {
 	}
 }
 
+func TestObjectsAssignedTo(t *testing.T) {
+	var data = `
+		var other = {id: 1, inner: {id: 2}};
+		window["ytInitialData"] = {id: 3, inner: {id: 4}};
+		var list = [{id: 5}];
+	`
+
+	var got []string
+
+	var cb = func(name string) JSONCallback {
+		return func(b []byte) error {
+			got = append(got, name+" "+string(b))
+			return nil
+		}
+	}
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Keys:       []string{"id"},
+			AssignedTo: "ytInitialData",
+			Callback:   cb("data"),
+		},
+		{
+			AssignedTo: "other",
+			Callback:   cb("other"),
+		},
+		{
+			// Objects within arrays are not assigned to the variable
+			AssignedTo: "list",
+			Callback:   cb("list"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []string{
+		`other {"id":1,"inner":{"id":2}}`,
+		`data {"id":3,"inner":{"id":4}}`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() called callbacks with %v, want %v", got, want)
+	}
+}
+
+func TestObjectsPath(t *testing.T) {
+	var data = `{
+		contents: [
+			{videoRenderer: {id: 1}},
+			{shelf: {items: [{videoRenderer: {id: 2}}]}},
+		],
+		sidebar: {videoRenderer: {id: 3}},
+		id: 4,
+	}`
+
+	var got []string
+
+	var cb = func(name string) JSONCallback {
+		return func(b []byte) error {
+			got = append(got, name+" "+string(b))
+			return nil
+		}
+	}
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Path:     "contents..videoRenderer",
+			Callback: cb("contents"),
+		},
+		{
+			Path:     "$.*.videoRenderer",
+			Callback: cb("sidebar"),
+		},
+		{
+			Keys:     []string{"id"},
+			Path:     "$",
+			Callback: cb("root"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []string{
+		`root {"contents":[{"videoRenderer":{"id":1}},{"shelf":{"items":[{"videoRenderer":{"id":2}}]}}],"sidebar":{"videoRenderer":{"id":3}},"id":4}`,
+		`contents {"id":1}`,
+		`contents {"id":2}`,
+		`sidebar {"id":3}`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() called callbacks with %v, want %v", got, want)
+	}
+
+	err = Objects(strings.NewReader(data), []ObjectOption{{Path: "a[", Callback: cb("invalid")}})
+	if err == nil {
+		t.Errorf("expected error for invalid path, but got nil")
+	}
+}
+
+func TestObjectsValues(t *testing.T) {
+	var data = `[
+		{type: "video", id: 1, price: 0},
+		{type: "video", id: 2, price: 3.5},
+		{type: "audio", id: 3, price: 2},
+		{type: "video", id: "4", price: 1},
+		{id: 5},
+	]`
+
+	var got []string
+
+	var cb = func(name string) JSONCallback {
+		return func(b []byte) error {
+			got = append(got, name+" "+string(b))
+			return nil
+		}
+	}
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Values: map[string]ValuePredicate{
+				"type":  Equals("video"),
+				"price": GreaterThan(0),
+				"id":    IsType(TypeNumber),
+			},
+			Callback: cb("paid video"),
+		},
+		{
+			Keys: []string{"id"},
+			Filter: func(m map[string]json.RawMessage) bool {
+				_, ok := m["type"]
+				return !ok
+			},
+			Callback: cb("untyped"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []string{
+		`paid video {"type":"video","id":2,"price":3.5}`,
+		`untyped {"id":5}`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() called callbacks with %v, want %v", got, want)
+	}
+}
+
+func TestObjectsNestedKeys(t *testing.T) {
+	var data = `[
+		{id: 1, author: {name: "A"}},
+		{id: 2, author: "B"},
+		{id: 3, snippet: {thumbnails: {"default": {url: "x"}}}},
+		{id: 4, "a.b": true},
+		{id: 5, a: {b: true}},
+		{id: 6, "c.d": {e: 1}},
+	]`
+
+	var got []string
+
+	var cb = func(name string) JSONCallback {
+		return func(b []byte) error {
+			got = append(got, name+" "+string(b))
+			return nil
+		}
+	}
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Keys:     []string{"id", "author.name"},
+			Callback: cb("author"),
+		},
+		{
+			Keys:     []string{"snippet.thumbnails.default.url"},
+			Callback: cb("thumbnail"),
+		},
+		{
+			Keys:     []string{`a\.b`},
+			Callback: cb("escaped"),
+		},
+		{
+			Keys:     []string{"a.b"},
+			Callback: cb("nested"),
+		},
+		{
+			Keys:     []string{`c\.d.e`},
+			Callback: cb("escaped nested"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []string{
+		`author {"id":1,"author":{"name":"A"}}`,
+		`thumbnail {"id":3,"snippet":{"thumbnails":{"default":{"url":"x"}}}}`,
+		`escaped {"id":4,"a.b":true}`,
+		`nested {"id":5,"a":{"b":true}}`,
+		`escaped nested {"id":6,"c.d":{"e":1}}`,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() called callbacks with %v, want %v", got, want)
+	}
+}
+
+func Test_splitKeyPath(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"a", []string{"a"}},
+		{"a.b.c", []string{"a", "b", "c"}},
+		{`a\.b`, []string{"a.b"}},
+		{`a\\.b`, []string{`a\`, "b"}},
+		{`a\b`, []string{`a\b`}},
+		{".a.", []string{"", "a", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := splitKeyPath(tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitKeyPath(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectsMatchCallback(t *testing.T) {
+	var data = "x = {\n  a: 1,\n  inner: [{b: 2}, {\"b\": 3}]\n}"
+
+	type pos struct {
+		data, raw    string
+		start, end   int64
+		line, column int
+	}
+
+	var got []pos
+
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			Keys: []string{"b"},
+			MatchCallback: func(m Match) error {
+				got = append(got, pos{string(m.Data), string(m.Raw), m.StartOffset, m.EndOffset, m.Line, m.Column})
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []pos{
+		{`{"b":2}`, `{b: 2}`, 24, 30, 3, 11},
+		{`{"b":3}`, `{"b": 3}`, 32, 40, 3, 19},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() reported matches %v, want %v", got, want)
+	}
+
+	for _, p := range got {
+		if source := data[p.start:p.end]; source != p.raw {
+			t.Errorf("offsets of %s point to %q, but Raw is %q", p.data, source, p.raw)
+		}
+	}
+}
+
+func TestObjectsMatchPath(t *testing.T) {
+	var data = `{contents: [{title: "a"}, {"video-renderer": {title: "b", list: [[{title: "c"}]]}}]}`
+
+	type result struct {
+		data, path string
+		depth      int
+		parentKey  string
+	}
+
+	var got []result
+	err := Objects(strings.NewReader(data), []ObjectOption{
+		{
+			MatchCallback: func(m Match) error {
+				got = append(got, result{string(m.Data), m.Path, m.Depth, m.ParentKey})
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var want = []result{
+		{`{"contents":[{"title":"a"},{"video-renderer":{"title":"b","list":[[{"title":"c"}]]}}]}`, "$", 0, ""},
+		{`{"title":"a"}`, "$.contents[0]", 2, "contents"},
+		{`{"video-renderer":{"title":"b","list":[[{"title":"c"}]]}}`, "$.contents[1]", 2, "contents"},
+		{`{"title":"b","list":[[{"title":"c"}]]}`, `$.contents[1]["video-renderer"]`, 3, "video-renderer"},
+		{`{"title":"c"}`, `$.contents[1]["video-renderer"].list[0][0]`, 6, "list"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() called callbacks with %v, want %v", got, want)
+	}
+}
+
+func TestObjectsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int
+	err := ObjectsContext(ctx, strings.NewReader(`{a: 1} {a: 2} {a: 3}`), []ObjectOption{
+		{
+			Keys: []string{"a"},
+			Callback: func(b []byte) error {
+				calls++
+				if calls == 2 {
+					cancel()
+				}
+				return nil
+			},
+			Required: true,
+		},
+	})
+	if err != context.Canceled {
+		t.Errorf("ObjectsContext() returned %v, want context.Canceled", err)
+	}
+	if calls != 2 {
+		t.Errorf("callback was called %d times, want 2", calls)
+	}
+}
+
 func TestObjects(t *testing.T) {
 	tests := []struct {
 		json     string
@@ -534,3 +908,53 @@ func TestObjectsHTMLPlaylist(t *testing.T) {
 		t.Errorf("Expected extraction of playlist data, but no data was extracted")
 	}
 }
+
+func TestWalkJSON(t *testing.T) {
+	tests := []struct {
+		input  string
+		keys   []string
+		values []string
+	}{
+		{
+			`{"a":1,"b":[1,{"c":"}"}],"d":"e\"f"}`,
+			[]string{"a", "b", "d"},
+			[]string{`1`, `[1,{"c":"}"}]`, `"e\"f"`},
+		},
+		{
+			`[ 1 , "two" , {"three": 3} , [4] ]`,
+			[]string{"", "", "", ""},
+			[]string{`1`, `"two"`, `{"three": 3}`, `[4]`},
+		},
+		{
+			`{}`,
+			nil,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(t.Name(), func(t *testing.T) {
+			var (
+				keys   []string
+				values []string
+			)
+
+			err := walkJSON([]byte(tt.input), func(key string, value []byte, off int) error {
+				if tt.input[off:off+len(value)] != string(value) {
+					t.Errorf("offset %d of value %s is wrong", off, string(value))
+				}
+
+				keys = append(keys, key)
+				values = append(values, string(value))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(keys, tt.keys) || !reflect.DeepEqual(values, tt.values) {
+				t.Errorf("walkJSON(%s) returned keys %q and values %q, want %q and %q", tt.input, keys, values, tt.keys, tt.values)
+			}
+		})
+	}
+}
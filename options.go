@@ -0,0 +1,246 @@
+package jsonextract
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// InfinityMode defines how the JavaScript values Infinity, +Infinity and -Infinity are converted, as JSON has no representation for them
+type InfinityMode int
+
+const (
+	// InfinityNull converts infinity values to null, just like NaN
+	InfinityNull InfinityMode = iota
+
+	// InfinityString converts infinity values to the strings "Infinity" and "-Infinity"
+	InfinityString
+
+	// InfinityNumber converts infinity values to the largest (or smallest) float64 number, which is 1.7976931348623157e+308
+	InfinityNumber
+
+	// InfinityDisallow skips objects that contain infinity values
+	InfinityDisallow
+)
+
+// convert returns the JSON representation of positive or negative infinity
+func (m InfinityMode) convert(negative bool) ([]byte, error) {
+	switch m {
+	case InfinityNull:
+		return []byte("null"), nil
+	case InfinityString:
+		if negative {
+			return []byte(`"-Infinity"`), nil
+		}
+		return []byte(`"Infinity"`), nil
+	case InfinityNumber:
+		var value = math.MaxFloat64
+		if negative {
+			value = -value
+		}
+		return strconv.AppendFloat(nil, value, 'g', -1, 64), nil
+	default:
+		return nil, fmt.Errorf("Infinity is not allowed")
+	}
+}
+
+// LeadingZeroMode defines how legacy number literals with a leading zero, e.g. 017, are interpreted
+type LeadingZeroMode int
+
+const (
+	// LeadingZeroOctal interprets numbers with a leading zero as octal numbers, just like JavaScript does.
+	// Numbers that contain the digits 8 or 9 can't be octal and are interpreted as decimal numbers instead,
+	// which can also have a fraction or exponent, e.g. 08.5
+	LeadingZeroOctal LeadingZeroMode = iota
+
+	// LeadingZeroDecimal interprets numbers with a leading zero as decimal numbers, e.g. 017 is converted to 17
+	LeadingZeroDecimal
+
+	// LeadingZeroDisallow skips objects that contain numbers with a leading zero
+	LeadingZeroDisallow
+)
+
+// convert returns the decimal representation of number, which must consist of a leading zero followed by digits.
+// Numbers that contain the digits 8 or 9 can also have a fraction or exponent
+func (m LeadingZeroMode) convert(number []byte) ([]byte, error) {
+	switch m {
+	case LeadingZeroOctal:
+		if bytes.IndexAny(number, "89") >= 0 {
+			return LeadingZeroDecimal.convert(number)
+		}
+
+		i, ok := new(big.Int).SetString(string(number), 8)
+		if !ok {
+			return nil, fmt.Errorf("invalid octal number %q", string(number))
+		}
+		return i.Append(nil, 10), nil
+	case LeadingZeroDecimal:
+		if number = bytes.TrimLeft(number, "0"); len(number) == 0 {
+			return []byte("0"), nil
+		}
+		// A fraction without digits, e.g. in 08., is not valid JSON
+		if i := bytes.IndexByte(number, '.'); i >= 0 && (i == len(number)-1 || number[i+1] < '0' || number[i+1] > '9') {
+			number = append(number[:i+1:i+1], append([]byte{'0'}, number[i+1:]...)...)
+		}
+		return number, nil
+	default:
+		return nil, fmt.Errorf("number %q with a leading zero is not allowed", string(number))
+	}
+}
+
+// TemplateMode defines how substitutions in template literals, e.g. ${name} in `Hello ${name}`, are converted
+type TemplateMode int
+
+const (
+	// TemplateReject skips objects that contain template literals with substitutions
+	TemplateReject TemplateMode = iota
+
+	// TemplateVerbatim keeps substitutions as they were written, e.g. `Hello ${name}` is converted to "Hello ${name}"
+	TemplateVerbatim
+
+	// TemplateSubstitute replaces substitutions with the values from ExtractOptions.TemplateVariables.
+	// Objects that contain substitutions that are not in the map are skipped
+	TemplateSubstitute
+)
+
+// ExtractOptions defines how forgiving the conversion of JavaScript objects to JSON is.
+//
+// The zero value is what Reader and Objects use: anything that can be converted to JSON is converted.
+// Setting one of the Disallow options makes sure objects that contain the disallowed notation are skipped instead.
+type ExtractOptions struct {
+	// Strict only accepts objects that are already valid JSON. If it is set, all Disallow options are treated as set
+	Strict bool
+
+	// DisallowComments skips objects that contain comments. By default, comments are removed
+	DisallowComments bool
+
+	// DisallowUnquotedKeys skips objects that contain identifiers, e.g. unquoted keys like in {key: "value"}.
+	// By default, they are converted to strings
+	DisallowUnquotedKeys bool
+
+	// DisallowSingleQuotes skips objects that contain single-quoted strings. By default, they are converted to double-quoted ones
+	DisallowSingleQuotes bool
+
+	// DisallowTemplateStrings skips objects that contain template literals (`text`). By default, they are converted to normal strings
+	DisallowTemplateStrings bool
+
+	// Templates defines how substitutions in template literals are converted. By default, objects that contain them are skipped
+	Templates TemplateMode
+
+	// TemplateVariables contains the values used by TemplateSubstitute. The key is the expression of the substitution
+	// without surrounding whitespace, e.g. for `Hello ${ user.name }` the value for "user.name" is used
+	TemplateVariables map[string]string
+
+	// DisallowConcatenation skips objects that contain strings joined with '+', e.g. "a" + "b". By default, they are joined to one string
+	DisallowConcatenation bool
+
+	// Arithmetic evaluates arithmetic with number constants, e.g. 60 * 60 * 24 or 1 / 2. By default, objects that contain it are skipped.
+	// Like concatenation, it skips objects where it uses integers that a float64 can't represent exactly, e.g. 18446744073709551617
+	Arithmetic bool
+
+	// DisallowTrailingCommas skips objects and arrays that have a trailing comma, e.g. [1, 2, ]. By default, the comma is removed
+	DisallowTrailingCommas bool
+
+	// DisallowUndefined skips objects that contain undefined. By default, it is converted to null
+	DisallowUndefined bool
+
+	// DisallowNaN skips objects that contain NaN. By default, it is converted to null
+	DisallowNaN bool
+
+	// DisallowRegex skips objects that contain regex patterns. By default, they are converted to strings
+	DisallowRegex bool
+
+	// DisallowBigInt skips objects that contain BigInt literals like 15n. By default, the suffix is removed
+	DisallowBigInt bool
+
+	// BigIntAsString converts BigInt literals like 15n to JSON strings ("15") instead of numbers.
+	// This is useful if the decoder can't hold numbers of arbitrary size
+	BigIntAsString bool
+
+	// Infinity defines how Infinity values are converted. By default, they are converted to null
+	Infinity InfinityMode
+
+	// LeadingZero defines how numbers with a leading zero, e.g. 017, are interpreted. By default, they are octal numbers
+	LeadingZero LeadingZeroMode
+
+	// DisallowNumberFormats skips objects that contain numbers that are not valid JSON numbers, e.g. 0x15, +3, - 5, 1_000 or 1.
+	// By default, they are converted to JSON numbers
+	DisallowNumberFormats bool
+
+	// Functions contains converters for function calls and constructors like new Date(0), by the name of the function.
+	// Names of methods contain their object, e.g. "Object.freeze", names of constructors start with "new ", e.g. "new Date".
+	// Objects that contain other calls are skipped.
+	// If this is nil, DefaultFunctions are used
+	Functions map[string]FunctionConverter
+
+	// UnwrapJSONParse extracts objects from string literals that are passed to JSON.parse, e.g. JSON.parse("{\"a\": 1}").
+	// Since the objects are not directly part of the input, their matches contain the unescaped text as Raw and the
+	// position of the string literal in the input. By default, such strings are searched like any other input
+	UnwrapJSONParse bool
+
+	// DescendStrings extracts objects from string values that contain encoded objects, e.g. {"payload": "{\"id\": 5}"}.
+	// Only strings that contain nothing but one object or array are used. The objects are passed to the callback after the object
+	// that contains them, which means that Objects can match their keys. Their matches have the position of that object
+	DescendStrings bool
+
+	// ReportAssignments sets Match.AssignedTo to the variable or property that each object or array is assigned to,
+	// e.g. ytInitialData for var ytInitialData = {...}. Values that are not assigned to anything have an empty AssignedTo
+	ReportAssignments bool
+
+	// HTMLAttributes makes HTMLReader also extract values from the attribute values of all elements,
+	// e.g. data-props="{&quot;id&quot;:1}". HTML entities are decoded first. Other functions ignore it
+	HTMLAttributes bool
+
+	// HTMLText makes HTMLReader also extract values from the text of elements other than scripts and styles,
+	// e.g. <div hidden>{&quot;id&quot;:1}</div>. HTML entities are decoded first. Other functions ignore it
+	HTMLText bool
+
+	// MaxObjectSize is the maximum size of an object or array in the input in bytes. Larger ones are skipped,
+	// but the objects within them are still extracted. Reading stops at the first part of the input that can't be
+	// part of an object, but input like "[" followed by a huge list of values is read until its end.
+	// This limits how much of the input is kept in memory. By default, objects can be of any size
+	MaxObjectSize int
+}
+
+// normalize returns the options that should actually be applied
+func (o ExtractOptions) normalize() ExtractOptions {
+	if o.Strict {
+		o.DisallowComments = true
+		o.DisallowUnquotedKeys = true
+		o.DisallowSingleQuotes = true
+		o.DisallowTemplateStrings = true
+		o.DisallowTrailingCommas = true
+		o.DisallowConcatenation = true
+		o.Arithmetic = false
+		o.DisallowUndefined = true
+		o.DisallowNaN = true
+		o.DisallowRegex = true
+		o.DisallowBigInt = true
+		o.DisallowNumberFormats = true
+		o.Infinity = InfinityDisallow
+		o.LeadingZero = LeadingZeroDisallow
+		o.Functions = map[string]FunctionConverter{}
+	}
+	if o.Functions == nil {
+		o.Functions = defaultFunctions
+	}
+	return o
+}
+
+// substitute returns the text a substitution with the given expression in a template literal is replaced with
+func (o ExtractOptions) substitute(expr []byte) (string, error) {
+	switch o.Templates {
+	case TemplateVerbatim:
+		return "${" + string(expr) + "}", nil
+	case TemplateSubstitute:
+		value, ok := o.TemplateVariables[string(bytes.TrimSpace(expr))]
+		if !ok {
+			return "", fmt.Errorf("no value for template substitution ${%s}", string(expr))
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("template substitutions are not allowed")
+	}
+}
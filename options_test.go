@@ -0,0 +1,255 @@
+package jsonextract
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func optionObjects(input string, opts ExtractOptions) (objects []json.RawMessage, err error) {
+	return objects, ReaderWithOptions(context.Background(), strings.NewReader(input), opts, func(m Match) error {
+		objects = append(objects, m.Data)
+		return nil
+	})
+}
+
+func TestReaderWithOptions(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  ExtractOptions
+		want  []json.RawMessage
+	}{
+		{
+			`{"a": 1, "b": [true, null]}`,
+			ExtractOptions{Strict: true},
+			[]json.RawMessage{
+				[]byte(`{"a":1,"b":[true,null]}`),
+			},
+		},
+		{
+			`{a: 1} {"a": 1}`,
+			ExtractOptions{Strict: true},
+			[]json.RawMessage{
+				[]byte(`{"a":1}`),
+			},
+		},
+		{
+			`{a: 1} {"a": 1}`,
+			ExtractOptions{DisallowUnquotedKeys: true},
+			[]json.RawMessage{
+				[]byte(`{"a":1}`),
+			},
+		},
+		{
+			`{"a": /* comment */ 1}`,
+			ExtractOptions{DisallowComments: true},
+			nil,
+		},
+		{
+			`{"a": /* comment */ 1}`,
+			ExtractOptions{DisallowRegex: true},
+			[]json.RawMessage{
+				[]byte(`{"a":1}`),
+			},
+		},
+		{
+			`["a", 'b']`,
+			ExtractOptions{DisallowSingleQuotes: true},
+			nil,
+		},
+		{
+			"[\"a\", `b`]",
+			ExtractOptions{DisallowTemplateStrings: true},
+			nil,
+		},
+		{
+			`[[1, 2, ], [3]]`,
+			ExtractOptions{DisallowTrailingCommas: true},
+			[]json.RawMessage{
+				[]byte(`[3]`),
+			},
+		},
+		{
+			`[[undefined], [NaN]]`,
+			ExtractOptions{DisallowUndefined: true},
+			[]json.RawMessage{
+				[]byte(`[null]`),
+			},
+		},
+		{
+			`[[undefined], [NaN]]`,
+			ExtractOptions{DisallowNaN: true},
+			[]json.RawMessage{
+				[]byte(`[null]`),
+			},
+		},
+		{
+			`{"a": /abc/}`,
+			ExtractOptions{DisallowRegex: true},
+			nil,
+		},
+		{
+			`[Infinity, -Infinity, +Infinity]`,
+			ExtractOptions{Infinity: InfinityString},
+			[]json.RawMessage{
+				[]byte(`["Infinity","-Infinity","Infinity"]`),
+			},
+		},
+		{
+			`{"a": -Infinity, "b": Infinity}`,
+			ExtractOptions{Infinity: InfinityNumber},
+			[]json.RawMessage{
+				[]byte(`{"a":-1.7976931348623157e+308,"b":1.7976931348623157e+308}`),
+			},
+		},
+		{
+			`[[Infinity], [NaN]]`,
+			ExtractOptions{Infinity: InfinityDisallow},
+			[]json.RawMessage{
+				[]byte(`[null]`),
+			},
+		},
+		{
+			`[[-Infinity], [1]]`,
+			ExtractOptions{Strict: true},
+			[]json.RawMessage{
+				[]byte(`[1]`),
+			},
+		},
+		{
+			"[`Hello ${name}!`, [1]]",
+			ExtractOptions{},
+			[]json.RawMessage{
+				[]byte(`[1]`),
+			},
+		},
+		{
+			"{\"a\": `Hello ${ user.name }!`}",
+			ExtractOptions{Templates: TemplateVerbatim},
+			[]json.RawMessage{
+				[]byte(`{"a":"Hello ${ user.name }!"}`),
+			},
+		},
+		{
+			"[`a ${`b ${c}`} d`]",
+			ExtractOptions{Templates: TemplateVerbatim},
+			[]json.RawMessage{
+				[]byte("[\"a ${`b ${c}`} d\"]"),
+			},
+		},
+		{
+			"{\"a\": `Hello ${ user.name }, you are ${age}`}",
+			ExtractOptions{Templates: TemplateSubstitute, TemplateVariables: map[string]string{"user.name": "Bob", "age": "42"}},
+			[]json.RawMessage{
+				[]byte(`{"a":"Hello Bob, you are 42"}`),
+			},
+		},
+		{
+			"[[`${a}${b}`], [`${a}`]]",
+			ExtractOptions{Templates: TemplateSubstitute, TemplateVariables: map[string]string{"a": "\"quoted\""}},
+			[]json.RawMessage{
+				[]byte(`["\"quoted\""]`),
+			},
+		},
+		{
+			"[`${a}`]",
+			ExtractOptions{Templates: TemplateVerbatim, DisallowTemplateStrings: true},
+			nil,
+		},
+		{
+			`[[15n], [15]]`,
+			ExtractOptions{DisallowBigInt: true},
+			[]json.RawMessage{
+				[]byte(`[15]`),
+			},
+		},
+		{
+			`[[0x15], [+3], [1.], [-21], [1.5e3]]`,
+			ExtractOptions{DisallowNumberFormats: true},
+			[]json.RawMessage{
+				[]byte(`[-21]`),
+				[]byte(`[1.5e3]`),
+			},
+		},
+		{
+			"[[- 5], [-/* comment */5], [-\n5], [-5]]",
+			ExtractOptions{DisallowNumberFormats: true},
+			[]json.RawMessage{
+				[]byte(`[-5]`),
+			},
+		},
+		{
+			`{"a": - 5} {"a": -5}`,
+			ExtractOptions{Strict: true},
+			[]json.RawMessage{
+				[]byte(`{"a":-5}`),
+			},
+		},
+		{
+			`[- 5]`,
+			ExtractOptions{},
+			[]json.RawMessage{
+				[]byte(`[-5]`),
+			},
+		},
+		{
+			`[[0x15], [+3], [1.], [-21]]`,
+			ExtractOptions{},
+			[]json.RawMessage{
+				[]byte(`[[21],[3],[1.0],[-21]]`),
+			},
+		},
+		{
+			`[{"a": 1}, {"b": [2]}] [{"c": 3}]`,
+			ExtractOptions{MaxObjectSize: 12},
+			[]json.RawMessage{
+				[]byte(`{"a":1}`),
+				[]byte(`{"b":[2]}`),
+				[]byte(`[{"c":3}]`),
+			},
+		},
+		{
+			"[" + strings.Repeat(`{"a": 1}, `, 1000) + "]",
+			ExtractOptions{MaxObjectSize: 7},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(t.Name(), func(t *testing.T) {
+			got, err := optionObjects(tt.input, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReaderWithOptions(%q, %+v) = %v, want %v", tt.input, tt.opts, convert(got), convert(tt.want))
+			}
+		})
+	}
+}
+
+func TestObjectsWithOptions(t *testing.T) {
+	var calls int
+
+	err := ObjectsWithOptions(context.Background(), strings.NewReader(`{a: 1, inner: {"a": 2}} {"a": 3}`), ExtractOptions{Strict: true}, []ObjectOption{
+		{
+			Keys: []string{"a"},
+			Callback: func(b []byte) error {
+				calls++
+				if string(b) != `{"a":2}` && string(b) != `{"a":3}` {
+					t.Errorf("unexpected object %s", string(b))
+				}
+				return nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("callback was called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,203 @@
+package jsonextract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathElement is one step from a value to a value within it: either the key of an object or the index in an array
+type pathElement struct {
+	key string
+
+	// index is the index in an array, it is -1 for keys of objects
+	index int
+}
+
+// formatPath returns path in the syntax of ObjectOption.Path, e.g. $.contents[3].videoRenderer or $["a-b"]
+func formatPath(path []pathElement) string {
+	var b strings.Builder
+	b.WriteByte('$')
+
+	for _, e := range path {
+		switch {
+		case e.index >= 0:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(e.index))
+			b.WriteByte(']')
+		case isIdentifierName([]byte(e.key)):
+			b.WriteByte('.')
+			b.WriteString(e.key)
+		default:
+			quoted, _ := json.Marshal(e.key)
+			b.WriteByte('[')
+			b.Write(quoted)
+			b.WriteByte(']')
+		}
+	}
+
+	return b.String()
+}
+
+// lastKey returns the last key of an object in path, or an empty string if there is none
+func lastKey(path []pathElement) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].index < 0 {
+			return path[i].key
+		}
+	}
+	return ""
+}
+
+// selectorKind defines what a pathSelector matches
+type selectorKind int
+
+const (
+	// selectKey matches the key of an object
+	selectKey selectorKind = iota
+	// selectIndex matches the index in an array
+	selectIndex
+	// selectAny matches any key or index, it is written as *
+	selectAny
+	// selectDescendants matches any number of keys and indices, including none. It is written as ..
+	selectDescendants
+)
+
+// pathSelector is one part of a parsed ObjectOption.Path
+type pathSelector struct {
+	kind  selectorKind
+	key   string
+	index int
+}
+
+// parsePath parses a path like $.contents..videoRenderer or items[*]["a.b"], see ObjectOption.Path
+func parsePath(path string) (selectors []pathSelector, err error) {
+	var s = strings.TrimPrefix(path, "$")
+
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], ".."):
+			selectors = append(selectors, pathSelector{kind: selectDescendants})
+			i += 2
+
+			// ".." is the separator before the next part, except at the end
+			if i < len(s) && (s[i] == '.' || s[i] == '[') {
+				return nil, fmt.Errorf("unexpected %q after \"..\" at offset %d", s[i], i)
+			}
+			if i < len(s) {
+				var sel pathSelector
+				sel, i = parseName(s, i)
+				selectors = append(selectors, sel)
+			}
+		case s[i] == '.':
+			i++
+			if i == len(s) || s[i] == '.' || s[i] == '[' {
+				return nil, fmt.Errorf("missing key after '.' at offset %d", i-1)
+			}
+
+			var sel pathSelector
+			sel, i = parseName(s, i)
+			selectors = append(selectors, sel)
+		case s[i] == '[':
+			var sel pathSelector
+			sel, i, err = parseBracket(s, i)
+			if err != nil {
+				return nil, err
+			}
+			selectors = append(selectors, sel)
+		case i == 0:
+			// The first key doesn't need a dot
+			var sel pathSelector
+			sel, i = parseName(s, i)
+			selectors = append(selectors, sel)
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", s[i], i)
+		}
+	}
+
+	return selectors, nil
+}
+
+// parseName parses the key or wildcard starting at s[i], which ends before the next '.' or '['. It returns the index after it
+func parseName(s string, i int) (sel pathSelector, end int) {
+	end = i
+	for end < len(s) && s[end] != '.' && s[end] != '[' {
+		end++
+	}
+
+	if name := s[i:end]; name != "*" {
+		return pathSelector{kind: selectKey, key: name}, end
+	}
+	return pathSelector{kind: selectAny}, end
+}
+
+// parseBracket parses the index, wildcard or quoted key in the brackets starting at s[i]. It returns the index after the closing bracket
+func parseBracket(s string, i int) (sel pathSelector, end int, err error) {
+	var content = s[i+1:]
+
+	if len(content) > 0 && (content[0] == '"' || content[0] == '\'') {
+		// The closing quote is the first one that is not escaped
+		var q = 1
+		for ; q < len(content) && content[q] != content[0]; q++ {
+			if content[q] == '\\' {
+				q++
+			}
+		}
+		if q+1 >= len(content) || content[q+1] != ']' {
+			return sel, 0, fmt.Errorf("unterminated bracket at offset %d", i)
+		}
+
+		key, err := unquoteJSString([]byte(content[:q+1]))
+		if err != nil {
+			return sel, 0, err
+		}
+		return pathSelector{kind: selectKey, key: string(key)}, i + q + 3, nil
+	}
+
+	closing := strings.IndexByte(content, ']')
+	if closing < 0 {
+		return sel, 0, fmt.Errorf("unterminated bracket at offset %d", i)
+	}
+	end = i + closing + 2
+
+	if content[:closing] == "*" {
+		return pathSelector{kind: selectAny}, end, nil
+	}
+
+	index, err := strconv.Atoi(content[:closing])
+	if err != nil || index < 0 {
+		return sel, 0, fmt.Errorf("invalid index %q at offset %d", content[:closing], i)
+	}
+	return pathSelector{kind: selectIndex, index: index}, end, nil
+}
+
+// matches returns whether the selector, which must not be selectDescendants, accepts e
+func (s pathSelector) matches(e pathElement) bool {
+	switch s.kind {
+	case selectKey:
+		return e.index < 0 && e.key == s.key
+	case selectIndex:
+		return e.index == s.index
+	default:
+		return true
+	}
+}
+
+// matchPath returns whether selectors accept the complete path
+func matchPath(selectors []pathSelector, path []pathElement) bool {
+	if len(selectors) == 0 {
+		return len(path) == 0
+	}
+
+	if selectors[0].kind == selectDescendants {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(selectors[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return len(path) > 0 && selectors[0].matches(path[0]) && matchPath(selectors[1:], path[1:])
+}
@@ -0,0 +1,107 @@
+package jsonextract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parsePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []pathSelector
+		wantErr bool
+	}{
+		{"$", nil, false},
+		{"a", []pathSelector{{kind: selectKey, key: "a"}}, false},
+		{"$.a.b", []pathSelector{{kind: selectKey, key: "a"}, {kind: selectKey, key: "b"}}, false},
+		{"a[3].*", []pathSelector{{kind: selectKey, key: "a"}, {kind: selectIndex, index: 3}, {kind: selectAny}}, false},
+		{
+			"contents..videoRenderer",
+			[]pathSelector{{kind: selectKey, key: "contents"}, {kind: selectDescendants}, {kind: selectKey, key: "videoRenderer"}},
+			false,
+		},
+		{"..id", []pathSelector{{kind: selectDescendants}, {kind: selectKey, key: "id"}}, false},
+		{"$..", []pathSelector{{kind: selectDescendants}}, false},
+		{`$["a.b"]['c]'][*]`, []pathSelector{{kind: selectKey, key: "a.b"}, {kind: selectKey, key: "c]"}, {kind: selectAny}}, false},
+		{`["say \"hi\""]`, []pathSelector{{kind: selectKey, key: `say "hi"`}}, false},
+		{"a.", nil, true},
+		{"a...b", nil, true},
+		{"a[", nil, true},
+		{"a[-1]", nil, true},
+		{"a[b]", nil, true},
+		{`a["b]`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_matchPath(t *testing.T) {
+	var path = []pathElement{{key: "contents", index: -1}, {index: 3}, {key: "videoRenderer", index: -1}}
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"contents[3].videoRenderer", true},
+		{"$.contents.*.videoRenderer", true},
+		{"contents..videoRenderer", true},
+		{"..videoRenderer", true},
+		{"..", true},
+		{"contents..", true},
+		{"contents[3]..videoRenderer", true},
+		{"contents[2].videoRenderer", false},
+		{"contents.videoRenderer", false},
+		{"videoRenderer", false},
+		{"contents[3]", false},
+		{"$", false},
+		{"..contents", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selectors, err := parsePath(tt.selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := matchPath(selectors, path); got != tt.want {
+				t.Errorf("matchPath(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatPath(t *testing.T) {
+	tests := []struct {
+		path []pathElement
+		want string
+	}{
+		{nil, "$"},
+		{[]pathElement{{key: "contents", index: -1}, {index: 3}, {key: "videoRenderer", index: -1}}, "$.contents[3].videoRenderer"},
+		{[]pathElement{{key: "a-b", index: -1}, {key: "", index: -1}, {key: `"`, index: -1}}, `$["a-b"][""]["\""]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := formatPath(tt.path)
+			if got != tt.want {
+				t.Errorf("formatPath() = %q, want %q", got, tt.want)
+			}
+
+			// The path must select the same location again
+			selectors, err := parsePath(got)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", got, err)
+			}
+			if !matchPath(selectors, tt.path) {
+				t.Errorf("path %q doesn't match the location it was created from", got)
+			}
+		})
+	}
+}
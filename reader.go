@@ -1,13 +1,13 @@
 package jsonextract
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -45,199 +45,376 @@ type JSONCallback func([]byte) error
 // The error will be returned, except if it is ErrStop which will cause the method to return nil.
 //
 // Please note that the reader must return UTF-8 bytes for this to work correctly.
+// Input in other encodings can be converted using DecodeReader.
 func Reader(reader io.Reader, callback JSONCallback) (err error) {
+	return ReaderContext(context.Background(), reader, callback)
+}
 
-	// Need to buffer in order to be able to unread invalid sections
-	buffered := newResettableBuffer(reader)
+// ReaderContext works like Reader, but stops processing when ctx is done.
+// In that case, ctx.Err() is returned.
+//
+// Please note that the context is only checked while processing data, not while waiting for data from reader.
+func ReaderContext(ctx context.Context, reader io.Reader, callback JSONCallback) (err error) {
+	return ReaderWithOptions(ctx, reader, ExtractOptions{}, func(m Match) error {
+		return callback(m.Data)
+	})
+}
 
-	var r rune
+// ReaderMatches works like Reader, but also passes the position of each object in the input to callback.
+// The original text of the object, before it was converted to JSON, is also available.
+//
+// Only the data of the object that is currently being looked at is kept in memory, which means that memory usage
+// is proportional to the largest object in the input, not the size of the input.
+func ReaderMatches(reader io.Reader, callback MatchCallback) (err error) {
+	return ReaderWithOptions(context.Background(), reader, ExtractOptions{}, callback)
+}
 
-	for {
-		// Read character by character
-		r, _, err = buffered.ReadRune()
-		if err != nil {
-			break
+// ReaderWithOptions is the most flexible version of Reader. It stops when ctx is done, converts objects
+// as defined by opts and passes matches with their position to callback, just like ReaderMatches.
+func ReaderWithOptions(ctx context.Context, reader io.Reader, opts ExtractOptions, callback MatchCallback) (err error) {
+	opts = opts.normalize()
+
+	var (
+		w = newWindow(reader)
+
+		// pos is the position of w.Bytes() in the input
+		pos = newPosition()
+
+		// failed contains the input offsets of opening brackets that are already known not to start an object.
+		// Without it, input like many opening brackets without closing ones would be read over and over again
+		failed = make(map[int64]bool)
+
+		// unterminated contains tokens like comments that continue until the end of the input. Objects that
+		// contain such a token fail without reading the rest of the input again
+		unterminated = newUnterminatedTokens()
+	)
+
+	if opts.ReportAssignments {
+		w.lookback = maxAssignmentLength
+	}
+
+	// fill is the number of bytes that should be available at the start of each iteration
+	for fill := 1; ; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		w.Fill(fill)
+		fill = 1
+
+		var data = w.Bytes()
+		if len(data) == 0 {
+			return w.Err()
 		}
 
 		// We're looking for opening brackets
-		if r == openArray || r == openObject {
+		i := bytes.IndexAny(data, "{[")
 
-			// We go back one rune so the JavaScript decoder will also read the opening brace
-			err = buffered.UnreadRune()
-			if err != nil {
-				break
+		if opts.UnwrapJSONParse {
+			var end = i
+			if end < 0 {
+				end = len(data)
 			}
 
-			// Mark the start of our object. We can return here in case of errors
-			buffered.MarkStart()
+			if j := bytes.Index(data[:end], jsonParseCall); j >= 0 {
+				pos.advance(data[:j])
+				w.Advance(j)
 
-			var (
-				msg           []byte
-				readByteCount int
-			)
+				var target string
+				if opts.ReportAssignments {
+					target = assignmentTarget(w.Before(), pos.offset == int64(len(w.Before())))
+				}
+
+				start, n, perr := readJSONParseArgument(w)
+				if perr != nil {
+					// There might still be objects after the function name
+					pos.advance(jsonParseCall)
+					w.Advance(len(jsonParseCall))
+					continue
+				}
 
-			// Now we interpret the next bytes as JS object and convert them into JSON
-			// since readJSObject might return invalid JSON, we must check the output
-			msg, readByteCount, err = readJSObject(buffered)
+				var litPos = pos
+				litPos.advance(w.Bytes()[:start])
 
-			if err != nil || !json.Valid(msg) {
-				// OK, so we tried to parse, but it didn't work.
-				// We now just skip this opening brace and check the following data
-				err = buffered.ReturnAndSkipOne()
-				if err != nil {
-					break
+				stopped, uerr := unwrapJSONParse(ctx, w.Bytes()[start:start+n], litPos, target, opts, callback)
+				if uerr != nil || stopped {
+					return uerr
 				}
 
+				// The string literal was completely read, we won't look at brackets within it
+				var skipped = w.Bytes()[:start+n]
+				if len(failed) > 0 {
+					for i, c := range skipped {
+						if c == openObject || c == openArray {
+							delete(failed, pos.offset+int64(i))
+						}
+					}
+				}
+
+				pos.advance(skipped)
+				w.Advance(len(skipped))
 				continue
 			}
 
-			// we read a certain amount of data that we should skip in the next round,
-			// but we should restore anything we read that wasn't part of the object we returned
-			// It is important to note that len(msg) is only equal to readByteCount if the
-			// original io.Reader already contained a valid JSON object, but not if it was an JS object
-			err = buffered.ReturnAndSkip(readByteCount)
-			if err != nil {
-				break
+			if i < 0 && !w.Complete() {
+				// The function name might start at the end of data, so we keep that part until more data is available
+				var keep = len(jsonParseCall) - 1
+				if keep > len(data) {
+					keep = len(data)
+				}
+
+				pos.advance(data[:len(data)-keep])
+				w.Advance(len(data) - keep)
+
+				fill = keep + 1
+				continue
 			}
+		}
 
-			// Call the callback
-			err = callback(msg)
-			if err != nil {
-				// ErrStop just stops, returns nil
-				if err == ErrStop {
-					err = nil
+		if i < 0 {
+			// Nothing interesting here, we can throw all of it away
+			pos.advance(data)
+			w.Advance(len(data))
+			continue
+		}
+
+		pos.advance(data[:i])
+		w.Advance(i)
+
+		if failed[pos.offset] {
+			delete(failed, pos.offset)
+
+			pos.advanceByte(data[i])
+			w.Advance(1)
+
+			continue
+		}
+
+		// Now we interpret the next bytes as JS object and convert them into JSON
+		// If ctx is done while reading, we'll notice at the start of the next iteration
+		msg, raw, brackets, rerr := readObject(ctx, w, pos.offset, unterminated, opts)
+		if rerr != nil {
+			// OK, so we tried to parse, but it didn't work.
+			// Objects starting within the data we just read might fail for the same reason,
+			// so we remember them to avoid trying again
+			for _, off := range failedStarts(msg, brackets, rerr) {
+				failed[pos.offset+int64(off)] = true
+			}
+
+			// We now just skip this opening brace and check the following data
+			pos.advanceByte(w.Bytes()[0])
+			w.Advance(1)
+
+			continue
+		}
+
+		// Opening brackets within this object will never be looked at
+		if len(failed) > 0 {
+			for i, c := range raw {
+				if c == openObject || c == openArray {
+					delete(failed, pos.offset+int64(i))
 				}
-				// The returned error
-				return err
 			}
+		}
 
-			buffered.MarkEnd()
+		var match = Match{
+			Data:        msg,
+			Raw:         raw,
+			StartOffset: pos.offset,
+			EndOffset:   pos.offset + int64(len(raw)),
+			Line:        pos.line,
+			Column:      pos.column,
+			brackets:    &brackets,
+		}
+		if opts.ReportAssignments {
+			match.AssignedTo = assignmentTarget(w.Before(), pos.offset == int64(len(w.Before())))
 		}
-	}
 
-	if err == io.EOF {
-		err = nil
-	}
+		// We continue right after the object we just read
+		pos.advance(raw)
+		w.Advance(len(raw))
 
-	return
-}
+		// Call the callback
+		err = callback(match)
+		if err != nil {
+			// ErrStop just stops, returns nil
+			if err == ErrStop {
+				err = nil
+			}
+			// The returned error
+			return err
+		}
 
-// resettableRuneBuffer allows reading from a buffer, then resetting certain parts
-type resettableRuneBuffer struct {
-	// normalBuffer is just the normal buffered reader. It is used because it allows unreading runes
-	normalBuffer *bufio.Reader
+		if opts.DescendStrings {
+			stopped, derr := descendStrings(ctx, match, opts, callback)
+			if derr != nil || stopped {
+				return derr
+			}
+		}
+	}
+}
 
-	// bufBefore stores all data that was read until we return to the beginning of an object
-	bufBefore *bytes.Buffer
+// readWithin extracts all objects from r, which contains data that was found within the input, e.g. the content
+// of a string literal, and passes them to callback. place is called for each match before callback and sets its
+// position in the input. If place returns an error, reading stops with that error.
+// It returns whether the callback returned ErrStop
+func readWithin(ctx context.Context, r io.Reader, opts ExtractOptions, place func(m *Match) error, callback MatchCallback) (stopped bool, err error) {
+	err = ReaderWithOptions(ctx, r, opts, func(m Match) error {
+		if err := place(&m); err != nil {
+			return err
+		}
 
-	// returnBuffer contains the content of bufBefore after we returned to a certain part we read before
-	returnBuffer *bytes.Buffer
+		err := callback(m)
+		if err == ErrStop {
+			stopped = true
+		}
+		return err
+	})
 
-	// enableReturn defines whether the buffer should log what is read through it.
-	// if true, one can return to any position after it was enabled
-	enableReturn bool
+	return stopped, err
 }
 
-func newResettableBuffer(r io.Reader) *resettableRuneBuffer {
-	bir, ok := r.(*bufio.Reader)
-	if !ok {
-		bir = bufio.NewReader(r)
+// failedStarts returns the offsets of all opening brackets in the input of a failed readObject call
+// that would fail for the same reason when trying to read an object from them.
+//
+// Such an opening bracket is one that was not closed before the error, or one where the
+// first invalid part of output lies between it and its closing bracket.
+func failedStarts(output []byte, brackets sourceMap, err error) (offsets []int) {
+	// Only the first bracket, which we already know about. Objects within an object that is too large
+	// might still be small enough
+	if len(brackets.out) < 2 || err == errObjectTooLarge {
+		return nil
 	}
 
-	return &resettableRuneBuffer{
-		normalBuffer: bir,
-		returnBuffer: new(bytes.Buffer),
-		bufBefore:    new(bytes.Buffer),
-	}
-}
+	var (
+		// If the object was closed, the brackets that are still open might be closed after it
+		scanFailed = err != errInvalidJSON
+		invalidAt  = invalidJSONOffset(output)
 
-// Read implements io.Reader
-func (s *resettableRuneBuffer) Read(p []byte) (n int, err error) {
-	n, _ = s.returnBuffer.Read(p)
+		// openObjects and openArrays contain indices of opening brackets in brackets.out that weren't closed yet
+		openObjects, openArrays []int
+	)
 
-	if n < len(p) {
-		n2, err2 := s.normalBuffer.Read(p[n:])
+	// The first bracket is the one we tried to read from, it doesn't need to be returned
+	for i := 1; i < len(brackets.out); i++ {
+		var out = brackets.out[i]
 
-		n += n2
-		err = err2
-	}
+		var stack *[]int
+		switch output[out] {
+		case '{':
+			openObjects = append(openObjects, i)
+			continue
+		case '[':
+			openArrays = append(openArrays, i)
+			continue
+		case '}':
+			stack = &openObjects
+		case ']':
+			stack = &openArrays
+		}
 
-	if s.enableReturn {
-		s.bufBefore.Write(p[:n])
-	}
+		if len(*stack) == 0 {
+			continue
+		}
 
-	return n, err
-}
+		start := (*stack)[len(*stack)-1]
+		*stack = (*stack)[:len(*stack)-1]
 
-// ReadRune reads exactly one rune
-func (s *resettableRuneBuffer) ReadRune() (r rune, size int, err error) {
-	r, size, err = s.returnBuffer.ReadRune()
-	if err != nil {
-		r, size, err = s.normalBuffer.ReadRune()
+		if brackets.out[start] < invalidAt && invalidAt <= out {
+			offsets = append(offsets, brackets.in[start])
+		}
 	}
 
-	if s.enableReturn {
-		s.bufBefore.WriteRune(r)
+	// Any bracket that is still open has the same problem as the one we tried to read
+	for _, start := range append(openObjects, openArrays...) {
+		if scanFailed || brackets.out[start] < invalidAt {
+			offsets = append(offsets, brackets.in[start])
+		}
 	}
 
 	return
 }
 
-// UnreadRune unreads the last rune read with ReadRune
-func (s *resettableRuneBuffer) UnreadRune() (err error) {
-	if s.enableReturn {
-		_ = s.bufBefore.UnreadRune()
-	}
-
-	err = s.returnBuffer.UnreadRune()
-	if err == nil {
-		return
+// invalidJSONOffset returns the offset of the first byte in b that makes it invalid JSON.
+// If b is just cut off, len(b) is returned
+func invalidJSONOffset(b []byte) int {
+	// A null byte is invalid in any position, so the decoder will definitely return an error
+	var v json.RawMessage
+	serr, ok := json.Unmarshal(append(b[:len(b):len(b)], 0), &v).(*json.SyntaxError)
+	if !ok {
+		return len(b)
 	}
 
-	return s.normalBuffer.UnreadRune()
+	// The offset is the number of bytes read until the error occurred, which includes the invalid byte
+	return int(serr.Offset) - 1
 }
 
-// ReturnAndSkipOne returns the buffer to the last reset (or initial) from an outside perspective,
-// except that it skips one rune from the underlying stream
-func (s *resettableRuneBuffer) ReturnAndSkipOne() (err error) {
-	s.returnBuffer = s.bufBefore
-
-	// Skip one rune
-	_, _, err = s.returnBuffer.ReadRune()
+// errInvalidJSON is returned from readObject if the object could not be converted to valid JSON
+var errInvalidJSON = errors.New("object is not valid JSON")
 
-	s.bufBefore = new(bytes.Buffer)
+// errObjectTooLarge is returned from readObject if the object is larger than ExtractOptions.MaxObjectSize
+var errObjectTooLarge = errors.New("object is larger than the maximum object size")
 
-	return
-}
+// initialObjectSize is the number of bytes that are given to the lexer when starting to read an object
+const initialObjectSize = 1024
 
-// ReturnAndSkip returns the buffer to the last reset (or initial) from an outside perspective,
-// except that it skips `offset` bytes from the input
-func (s *resettableRuneBuffer) ReturnAndSkip(offset int) (err error) {
-	s.returnBuffer = s.bufBefore
+// readObject reads the JS object at the start of the data in w and converts it to JSON.
+// raw contains a copy of the input bytes that were converted.
+// If an error is returned, output and brackets describe what was read until the error occurred.
+//
+// As the lexer needs all data of the object at once, we start with a small part of the data. If that isn't
+// enough to read the entire object, we continue with twice the amount of data until the object or input ends.
+// Most objects are small, so the time needed doesn't depend on how much data the window currently holds.
+//
+// offset is the position of the data in the input. Tokens that continue until the end of the input are added to unterminated.
+// If the object is larger than opts.MaxObjectSize, errObjectTooLarge is returned
+func readObject(ctx context.Context, w *window, offset int64, unterminated *unterminatedTokens, opts ExtractOptions) (output, raw []byte, brackets sourceMap, err error) {
+	var (
+		r              = objectReader{offset: offset, unterminated: unterminated}
+		readInputBytes int
+	)
 
-	if offset > 0 {
-		_, err = io.CopyN(ioutil.Discard, s.returnBuffer, int64(offset))
-	}
+	// size is the number of bytes that should be available, limit is the number of bytes given to the lexer
+	for size, limit := 0, initialObjectSize; ; {
+		if opts.MaxObjectSize > 0 && limit > opts.MaxObjectSize {
+			size, limit = opts.MaxObjectSize, opts.MaxObjectSize
+		}
+		w.Fill(size)
 
-	s.bufBefore = new(bytes.Buffer)
+		var data = w.Bytes()
+		if len(data) > limit {
+			data = data[:limit]
+		}
 
-	return
-}
+		output, readInputBytes, brackets, err = r.read(ctx, data, opts)
+		if err == errIncomplete && (len(data) < len(w.Bytes()) || !w.Complete()) {
+			if opts.MaxObjectSize > 0 && len(data) >= opts.MaxObjectSize {
+				return output, nil, brackets, errObjectTooLarge
+			}
+			size = 2 * len(data)
+			limit = size
+			continue
+		}
+		if err == errIncomplete {
+			// All of the remaining input was read
+			unterminated.add(data, offset, r.cut)
+		}
+		if err != nil {
+			return
+		}
 
-// MarkStart marks a restart point. When calling a return method, this
-// start will be used
-func (s *resettableRuneBuffer) MarkStart() {
-	s.enableReturn = true
-	s.bufBefore.Reset()
-}
+		// since readJSObject might return invalid JSON, we must check the output
+		if !json.Valid(output) {
+			return output, nil, brackets, errInvalidJSON
+		}
 
-// MarkEnd disables returning until MarkStart is called the next time
-func (s *resettableRuneBuffer) MarkEnd() {
-	s.enableReturn = false
-	// bufBefore.Len() == 0 at this moment
+		// It is important to note that len(output) is only equal to readInputBytes if the
+		// input already contained a valid JSON object, but not if it was an JS object
+		raw = append([]byte(nil), data[:readInputBytes]...)
 
-	if s.bufBefore.Len() != 0 {
-		panic("wrong use of resettableRuneBuffer")
+		return
 	}
 }
 
@@ -268,16 +445,81 @@ var templateQuoteReplacer = strings.NewReplacer(
 	"\\`", "`",
 )
 
-// readJSObject converts the input data from `r` to JSON if possible.
+// errIncomplete is returned from readJSObject if the input ended before the object did
+var errIncomplete = errors.New("unexpected end of input")
+
+// lineCommentStart is the start of a comment that continues until the end of the line
+var lineCommentStart = []byte("//")
+
+// maxLexerLookahead is the maximum number of bytes the lexer looks ahead to decide on a token
+const maxLexerLookahead = 4
+
+// readJSObject converts the JS object at the start of `b` to JSON if possible.
 // Input data should either already be JSON or a JavaScript object declaration.
 // Please note that output might not be valid JSON and should be checked using json.Valid()
-func readJSObject(r io.Reader) (output []byte, readInputBytes int, err error) {
-	// Note: the current implementation of NewInput reads all bytes in the reader,
-	// which is problematic for large files
-	lex := js.NewLexer(parse.NewInput(r))
+//
+// The positions of all brackets in output are recorded in brackets.
+// If b ends before the object does, errIncomplete is returned.
+// On errors, output contains everything that was converted before the error occurred.
+// If ctx is done before the object was read, ctx.Err() is returned.
+// If the object contains notation that is disallowed by opts, an error is returned.
+func readJSObject(ctx context.Context, b []byte, opts ExtractOptions) (output []byte, readInputBytes int, brackets sourceMap, err error) {
+	var r objectReader
+	return r.read(ctx, b, opts)
+}
+
+// objectReader works like readJSObject, but can continue reading an object that was cut off.
+// If read returns errIncomplete, calling it again with more data of the same input
+// continues from the last token before the end instead of reading everything again
+type objectReader struct {
+	buf      bytes.Buffer
+	brackets sourceMap
+
+	// cp is the last state where reading can continue, it is only used if resume is set
+	cp     readCheckpoint
+	resume bool
+
+	// offset is the position of the data given to read in the input. Objects that contain a token
+	// from unterminated fail with errUnterminated
+	offset       int64
+	unterminated *unterminatedTokens
+
+	// cut is the token that was cut off by the end of the data if read returned errIncomplete
+	cut cutToken
+
+	// grammar stops reading at the first token that can't be part of the object
+	grammar jsonGrammar
+}
+
+// readCheckpoint is the state of objectReader.read before reading a token. It can only be taken
+// when no function call or constant expression is being read, as their output might be rewritten later
+type readCheckpoint struct {
+	// offset is the input offset of the next token
+	offset, readInputBytes int
+
+	// outLen is the length of the output, tail is its last byte. Only the last byte can be removed by
+	// later tokens, e.g. a trailing comma
+	outLen int
+	tail   byte
+
+	bracketsLen  int
+	level, depth int
+	first        byte
+	lastByte     byte
+	lastToken    js.TokenType
+	spaced       bool
+	next         grammarState
+}
+
+// read works like readJSObject. Unless the last call returned errIncomplete, it starts reading a new object.
+// The returned output is only valid until the next call
+func (r *objectReader) read(ctx context.Context, b []byte, opts ExtractOptions) (output []byte, readInputBytes int, brackets sourceMap, err error) {
+	input := parse.NewInputBytes(b)
+	// NewInputBytes might temporarily overwrite the byte after b, we must restore it
+	defer input.Restore()
 
 	// buf stores the bytes that should be returned in output
-	var buf = new(bytes.Buffer)
+	var buf = &r.buf
 
 	var (
 		// since it's a dyck language, we just count the level of braces.
@@ -287,17 +529,133 @@ func readJSObject(r io.Reader) (output []byte, readInputBytes int, err error) {
 	)
 
 	// lastByte stores the last byte we wrote to buf
-	// It is used for detecting and correcting trailing commas
+	// It is used for detecting and correcting trailing commas.
+	// spaced is set if whitespace or comments were read after it
 	var (
 		lastByte  byte
 		lastToken js.TokenType
+		spaced    bool
+	)
+
+	// Constant expressions like "a" + "b" are folded while reading them.
+	// valueStart is the offset in buf where the last string or number we wrote starts, or -1 if the last token was something else
+	var (
+		valueStart = -1
+		expr       constExpr
+	)
+
+	// calls contains the function calls like new Date(0) whose arguments are currently being read.
+	// depth is the number of brackets that are currently open
+	var (
+		calls []callFrame
+		depth int
+	)
+
+	// Continue where the last call stopped or start from scratch
+	if cp := r.cp; r.resume && buf.Len() >= cp.outLen-1 {
+		input.Move(cp.offset)
+		input.Skip()
+
+		readInputBytes = cp.readInputBytes
+		if cp.outLen > 0 {
+			buf.Truncate(cp.outLen - 1)
+			buf.WriteByte(cp.tail)
+		} else {
+			buf.Reset()
+		}
+		brackets = r.brackets
+		brackets.out, brackets.in = brackets.out[:cp.bracketsLen], brackets.in[:cp.bracketsLen]
+
+		first, level, depth = cp.first, cp.level, cp.depth
+		lastByte, lastToken, spaced = cp.lastByte, cp.lastToken, cp.spaced
+		r.grammar.restore(buf.Bytes(), brackets.out, cp.next)
+	} else {
+		buf.Reset()
+		r.grammar.reset()
+	}
+	var grammar = &r.grammar
+
+	// value checks that a value of the given kind can come next.
+	// Operands of an expression are part of the value the expression started with
+	value := func(kind valueKind) error {
+		if expr.pending() {
+			return nil
+		}
+		return grammar.value(kind)
+	}
+
+	lex := js.NewLexer(input)
+
+	// tokenStart is the offset of the token that is currently being read, prevTokenStart the one of the token before
+	var tokenStart, prevTokenStart = -1, -1
+	r.cut = cutToken{start: -1}
+
+	defer func() {
+		r.brackets = brackets
+		r.resume = err == errIncomplete
+	}()
+
+	var (
+		merr error
+		done = ctx.Done()
 	)
-	var merr error
 loop:
 	for {
-		tt, text := lex.Next()
+		select {
+		case <-done:
+			return nil, 0, sourceMap{}, ctx.Err()
+		default:
+		}
+
+		// Remember this state in case the input ends before the object does.
+		// A token that ends right at the end of b might continue after it, e.g. a line comment
+		if len(calls) == 0 && valueStart < 0 && !expr.pending() && (readInputBytes == 0 || input.Offset()+maxLexerLookahead < len(b)) {
+			r.cp = readCheckpoint{
+				offset:         input.Offset(),
+				readInputBytes: readInputBytes,
+				outLen:         buf.Len(),
+				bracketsLen:    len(brackets.out),
+				level:          level,
+				depth:          depth,
+				first:          first,
+				lastByte:       lastByte,
+				lastToken:      lastToken,
+				spaced:         spaced,
+				next:           grammar.next,
+			}
+			if buf.Len() > 0 {
+				r.cp.tail = buf.Bytes()[buf.Len()-1]
+			}
+		}
+
+		prevTokenStart, tokenStart = tokenStart, input.Offset()
+		if r.unterminated.token(b[tokenStart:], r.offset+int64(tokenStart)) {
+			err = errUnterminated
+			break loop
+		}
+
+		var (
+			tt   js.TokenType
+			text []byte
+			// operandStart is the offset in buf where the current token was written if it is a string or number
+			operandStart = -1
+		)
+		// The lexer rejects legacy octal numbers like 017, so we read them ourselves
+		if n := legacyOctalLength(b[input.Offset():]); readInputBytes > 0 && n > 0 {
+			tt, text = js.IntegerToken, b[input.Offset():input.Offset()+n]
+			input.Move(n)
+			input.Skip()
+		} else {
+			tt, text = lex.Next()
+		}
 		if tt == js.ErrorToken {
 			err = lex.Err()
+			if err == io.EOF && prevTokenStart >= 0 && bytes.HasPrefix(b[prevTokenStart:], lineCommentStart) {
+				// The object can't end after a line comment that continues until the end of the input
+				r.cut.start = prevTokenStart
+			} else if err != io.EOF && input.Offset() == len(b) {
+				r.cut.start = tokenStart
+			}
 			break loop
 		}
 
@@ -312,13 +670,95 @@ loop:
 
 		switch {
 		case isIgnoredToken(tt):
+			if opts.DisallowComments && (tt == js.CommentToken || tt == js.CommentLineTerminatorToken) {
+				err = fmt.Errorf("comments are not allowed")
+				break loop
+			}
+
 			// Ignore tokens that are not needed for JSON.
 			// We must continue so they are not seen as last written byte
+			spaced = true
 			continue
+		case tt == js.NewToken || js.IsIdentifier(tt) && startsCall(b[input.Offset():]):
+			// A function call or constructor, e.g. new Date(0). We read the name of the function,
+			// its arguments are then read like an array that is converted when the call ends
+			var (
+				name        = string(text)
+				constructor = tt == js.NewToken
+			)
+			if constructor {
+				name = ""
+			}
+
+			for tt != js.OpenParenToken {
+				tt, text = nextToken(lex, &readInputBytes)
+				switch {
+				case tt == js.ErrorToken:
+					err = lex.Err()
+				case tt == js.DotToken && name != "":
+					name += "."
+				case js.IsIdentifierName(tt) && (name == "" || name[len(name)-1] == '.'):
+					name += string(text)
+				case tt != js.OpenParenToken || name == "" || name[len(name)-1] == '.':
+					err = fmt.Errorf("unexpected token %q in function call", string(text))
+				}
+				if err != nil {
+					break loop
+				}
+			}
+
+			// Constructors can behave differently than calls, e.g. Date(0) returns the current time instead of the date
+			if constructor {
+				name = "new " + name
+			}
+
+			convert, ok := opts.Functions[name]
+			if !ok {
+				err = fmt.Errorf("function %s is not supported", name)
+				break loop
+			}
+
+			if err = grammar.openBracket('('); err != nil {
+				break loop
+			}
+			calls = append(calls, callFrame{convert: convert, start: buf.Len(), depth: depth})
+
+			text = []byte{'['}
+			buf.Write(text)
 		case js.IsIdentifier(tt):
 			// Certain keywords are reserved in JSON. As a special case,
-			// we replace "undefined" with "null"
-			if val, ok := jsIdentifiers[string(text)]; ok {
+			// we replace "undefined" with "null". Used as keys, e.g. in {Infinity: 1}, they are quoted like any other identifier.
+			// The startsCall case above makes sure that something follows the identifier in b
+			var key = b[skipJSONSpace(b, input.Offset())] == ':'
+			if string(text) == "Infinity" && !key {
+				// Infinity has no JSON representation, so it is converted as the options say.
+				// A sign that was already written becomes part of the converted value
+				var kind = valueSigned
+				if opts.Infinity == InfinityString {
+					kind |= valueKey
+				}
+				if err = value(kind); err != nil {
+					break loop
+				}
+				var negative = lastByte == '-'
+				if lastByte == '+' || lastByte == '-' {
+					buf.Truncate(buf.Len() - 1)
+				}
+
+				text, err = opts.Infinity.convert(negative)
+				if err != nil {
+					break loop
+				}
+				buf.Write(text)
+			} else if val, ok := jsIdentifiers[string(text)]; ok && !key {
+				if opts.DisallowUndefined && string(text) == "undefined" || opts.DisallowNaN && string(text) == "NaN" {
+					err = fmt.Errorf("%s is not allowed", string(text))
+					break loop
+				}
+				if err = value(valueSigned); err != nil {
+					break loop
+				}
+
 				// Another special case: this handles stuff like -NaN, which would
 				// result in "-null", which is invalid JSON
 				if lastByte == '+' || lastByte == '-' {
@@ -329,6 +769,14 @@ loop:
 				// This is reached if we have an unquoted key in an object, e.g.
 				//     { key: "value" }
 				// We want to quote this identifier, as in marshal it into a string
+				if opts.DisallowUnquotedKeys {
+					err = fmt.Errorf("unquoted key %q is not allowed", string(text))
+					break loop
+				}
+				if err = value(valueKey); err != nil {
+					break loop
+				}
+
 				text, merr = json.Marshal(string(text))
 				if merr != nil {
 					err = merr
@@ -336,12 +784,42 @@ loop:
 				}
 				buf.Write(text)
 			}
+		case tt == js.DivToken && valueStart >= 0 && opts.Arithmetic:
+			// A division of constants, e.g. 1 / 2
+			if err = expr.addOperator(buf.Bytes(), valueStart, '/'); err != nil {
+				break loop
+			}
+			valueStart = -1
+			lastToken = tt
+			continue
 		case tt == js.DivToken || tt == js.DivEqToken:
 			// It is important that this comes before the IsPunctuator check
 			// Basically if we find a '/', we suspect it's a regex
+			if opts.DisallowRegex {
+				err = fmt.Errorf("regex patterns are not allowed")
+				break loop
+			}
+
+			var divLength = len(text)
+
+			if r.unterminated.regex(b[tokenStart:], r.offset+int64(tokenStart)) {
+				err = errUnterminated
+				break loop
+			}
+
 			tt, text = lex.RegExp()
 			if tt != js.RegExpToken {
 				err = fmt.Errorf("expected regex token when starting with '/', but was %s (lex err: %w)", tt.String(), lex.Err())
+				if input.Offset() == len(b) {
+					r.cut = cutToken{start: tokenStart, regex: true}
+				}
+				break loop
+			}
+
+			// The regex token also contains the division token we already counted
+			readInputBytes += len(text) - divLength
+
+			if err = value(valueKey); err != nil {
 				break loop
 			}
 
@@ -359,50 +837,118 @@ loop:
 				break loop
 			}
 
+			// This must be checked before brackets are recorded, as objects starting with them can still be valid
+			if expr.pending() && tt != js.AddToken && tt != js.SubToken {
+				err = expr.errMissingOperand()
+				break loop
+			}
+
 			switch text[0] {
 			case '{', '[':
 				if text[0] == first {
 					level++
 				}
 
-				if lastByte == '{' && text[0] == '{' {
-					err = fmt.Errorf("opening brace { cannot come after another opening brace")
+				if err = grammar.openBracket(text[0]); err != nil {
 					break loop
 				}
 
+				depth++
+				brackets.add(buf.Len(), readInputBytes-len(text))
 				buf.Write(text)
 			case ']', '}':
+				if err = grammar.closeBracket(text[0]); err != nil {
+					break loop
+				}
+
 				if text[0] == matchingBracket[first] {
 					level--
 				}
+				depth--
 
 				// An array/object with trailing comma was found.
 				// Example: [1, 2, 3, ]
 				if lastByte == ',' {
+					if opts.DisallowTrailingCommas {
+						err = fmt.Errorf("trailing commas are not allowed")
+						break loop
+					}
+
 					// We remove the comma to also support those objects.
 					buf.Truncate(buf.Len() - 1)
 				}
 
+				brackets.add(buf.Len(), readInputBytes-len(text))
 				buf.Write(text)
 
 				// We finished the JS object that was started with `first`. Time to stop
 				if level == 0 {
 					break loop
 				}
-			case '+':
-				if '0' <= lastByte && lastByte <= '9' {
-					err = fmt.Errorf("cannot use '+' to add numbers/strings")
+			case ')':
+				// Parentheses are only valid around the arguments of a function call
+				if err = grammar.closeBracket(text[0]); err != nil {
+					break loop
+				}
+
+				var call = calls[len(calls)-1]
+				calls = calls[:len(calls)-1]
+
+				// Arguments may have a trailing comma, just like arrays
+				if lastByte == ',' {
+					buf.Truncate(buf.Len() - 1)
+				}
+				buf.WriteByte(']')
+
+				// The call is replaced by its result
+				if text, err = call.result(buf.Bytes()[call.start:]); err != nil {
+					break loop
+				}
+
+				buf.Truncate(call.start)
+				brackets.truncate(call.start)
+				buf.Write(text)
+			case '+', '-', '*':
+				if valueStart >= 0 {
+					// An operator after a string or number, e.g. "a" + "b"
+					if err = expr.addOperator(buf.Bytes(), valueStart, text[0]); err != nil {
+						break loop
+					}
+					valueStart = -1
+					lastToken = tt
+					continue
+				}
+				if isValueEnd(lastToken) {
+					err = fmt.Errorf("cannot use %q on values other than string and number constants", text[0])
 					break loop
 				}
-				// continue with buf.Write
-				fallthrough
+				// This could e.g. be a "-" in front of a number. The sign of an operand is part of the operand
+				if !expr.pending() {
+					if err = grammar.punctuator(text[0]); err != nil {
+						break loop
+					}
+				}
+				buf.Write(text)
 			default:
-				// This could e.g. be a "-" in front of a number
+				// This could e.g. be a comma or colon
+				if err = grammar.punctuator(text[0]); err != nil {
+					break loop
+				}
 				buf.Write(text)
 			}
 		case tt == js.StringToken:
+			if err = value(valueKey); err != nil {
+				break loop
+			}
+			operandStart = buf.Len()
+
 			// Special quotes must be handled
 			if text[0] == '\'' {
+				if opts.DisallowSingleQuotes {
+					err = fmt.Errorf("single-quoted strings are not allowed")
+					break loop
+				}
+
 				buf.WriteString(singleQuoteReplacer.Replace(string(text)))
 				// Break out of switch to continue with the lastByte assignment below
 				break
@@ -418,10 +964,20 @@ loop:
 			err = fmt.Errorf("unsupported string type (text: %s)", string(text))
 			break loop
 		case tt == js.TemplateToken:
+			if opts.DisallowTemplateStrings {
+				err = fmt.Errorf("template literals are not allowed")
+				break loop
+			}
+
 			if len(text) <= 2 {
 				err = fmt.Errorf("expected string to have at least quotes, but that didn't happen")
 				break loop
 			}
+			if err = value(valueKey); err != nil {
+				break loop
+			}
+
+			operandStart = buf.Len()
 
 			var toEscape = templateQuoteReplacer.Replace(string(text[1 : len(text)-1]))
 
@@ -431,49 +987,276 @@ loop:
 				break loop
 			}
 
+			buf.Write(text)
+		case tt == js.TemplateStartToken:
+			if opts.DisallowTemplateStrings {
+				err = fmt.Errorf("template literals are not allowed")
+				break loop
+			}
+
+			if r.unterminated.template(r.offset + int64(tokenStart)) {
+				err = errUnterminated
+				break loop
+			}
+			if err = value(valueKey); err != nil {
+				break loop
+			}
+
+			// The template contains substitutions like `a ${b} c`, we read it completely.
+			// Brackets within substitutions are not part of the object, so they are not recorded
+			var (
+				str  string
+				n    int
+				open []int
+			)
+			str, n, open, err = readTemplate(lex, text, opts)
+			readInputBytes += n
+			if err != nil {
+				if input.Offset() == len(b) {
+					r.cut = cutToken{start: tokenStart, template: true, nested: open}
+				}
+				break loop
+			}
+
+			text, merr = json.Marshal(str)
+			if merr != nil {
+				err = merr
+				break loop
+			}
+
+			operandStart = buf.Len()
 			buf.Write(text)
 		case js.IsNumeric(tt):
-			if js.IsNumeric(lastToken) {
-				err = fmt.Errorf("invalid: writing two numbers directly after each other")
+			// Numbers can become keys if they are converted to strings, e.g. BigInt literals or 1 + "a"
+			if err = value(valueKey | valueSigned); err != nil {
 				break loop
 			}
 			// Not all JS numbers are valid JSON numbers, e.g. the following are valid in JS, but not JSON:
 			// +5, 0x3, 0o4, 0b1001, -0x3, 8n
+			if opts.DisallowBigInt && text[len(text)-1] == 'n' {
+				err = fmt.Errorf("BigInt literals are not allowed")
+				break loop
+			}
+			// A minus sign is only part of a JSON number if nothing is between them, e.g. "- 5" is not allowed
+			if opts.DisallowNumberFormats && (lastByte == '+' || lastByte == '-' && spaced || !json.Valid(text)) {
+				err = fmt.Errorf("number %q is not a valid JSON number", string(text))
+				break loop
+			}
 
 			// If the number starts with a '+', we already wrote it. Remove it again, as plus signs are not valid json numbers
 			if lastByte == '+' {
 				buf.Truncate(buf.Len() - 1)
 			}
 
-			switch tt {
-			case js.IntegerToken:
-				// BigIntegers can be written e.g. as "50n", "0x5n" etc.
-				text = bytes.TrimSuffix(text, []byte("n"))
-				fallthrough
-			default:
+			// BigIntegers can be written e.g. as "50n", "0x5n" etc.
+			var isBigInt = text[len(text)-1] == 'n'
+			if isBigInt {
+				text = text[:len(text)-1]
+			} else {
+				// A minus sign we already wrote is part of the value
+				operandStart = buf.Len()
+				if lastByte == '-' {
+					operandStart--
+				}
+			}
+
+			if tt == js.IntegerToken && isLegacyOctal(text) {
+				text, err = opts.LeadingZero.convert(text)
+				if err != nil {
+					break loop
+				}
+			} else {
 				text = transformNumber(text)
 			}
 
+			if isBigInt && opts.BigIntAsString {
+				// A minus sign that was already written must be part of the string
+				if lastByte == '-' {
+					buf.Truncate(buf.Len() - 1)
+					buf.WriteString(`"-`)
+				} else {
+					buf.WriteByte('"')
+				}
+				buf.Write(text)
+				text = []byte{'"'}
+			}
 			buf.Write(text)
-		default:
-			// There shouldn't be much left. But in case it's valid JSON, we keep it
+		case tt == js.TrueToken || tt == js.FalseToken || tt == js.NullToken:
+			if err = value(0); err != nil {
+				break loop
+			}
 			buf.Write(text)
+		default:
+			// Other keywords like function can't be part of JSON
+			err = fmt.Errorf("unexpected token %q in JS value", string(text))
+			break loop
+		}
+
+		switch {
+		case operandStart >= 0 && expr.pending():
+			// This value is the next operand of an expression, the expression is replaced with its result
+			text, err = expr.addOperand(buf, operandStart, !opts.DisallowConcatenation, opts.Arithmetic)
+			if err != nil {
+				break loop
+			}
+			valueStart = expr.start
+		case expr.pending() && (tt == js.AddToken || tt == js.SubToken):
+			// The sign of the next operand
+		case expr.pending():
+			err = expr.errMissingOperand()
+			break loop
+		default:
+			// This token is not part of an expression, but a new one might start with it
+			expr.reset()
+			valueStart = operandStart
 		}
 
 		lastByte = text[len(text)-1]
 		lastToken = tt
+		spaced = false
 	}
 
-	if err == nil || err == io.EOF {
-		return buf.Bytes(), readInputBytes, nil
+	output = buf.Bytes()
+
+	// Errors at the end of b might be caused by data that is missing after it,
+	// e.g. a string where the closing quote is not part of b
+	if err == io.EOF || err != nil && err != errUnterminated && input.Offset()+maxLexerLookahead >= len(b) {
+		err = errIncomplete
 	}
-	return nil, 0, err
+
+	return
+}
+
+// readTemplate reads the rest of a template literal with substitutions after its TemplateStartToken start was read.
+// It returns the text between the backticks with substitutions converted as defined by opts
+// and the number of bytes that were read after start.
+// If an error is returned, open contains the offsets of the templates nested within the current substitution
+// that were not closed, relative to the start of the template
+func readTemplate(lex *js.Lexer, start []byte, opts ExtractOptions) (text string, n int, open []int, err error) {
+	var (
+		sb   strings.Builder
+		expr bytes.Buffer
+	)
+
+	// start is "`text${"
+	sb.WriteString(templateQuoteReplacer.Replace(string(start[1 : len(start)-2])))
+
+	for {
+		tt, token := lex.Next()
+		if tt == js.ErrorToken {
+			return "", n, open, lex.Err()
+		}
+		n += len(token)
+
+		switch {
+		case tt == js.TemplateStartToken:
+			open = append(open, len(start)+n-len(token))
+		case (tt == js.TemplateMiddleToken || tt == js.TemplateEndToken) && len(open) > 0:
+			if tt == js.TemplateEndToken {
+				open = open[:len(open)-1]
+			}
+		case tt == js.TemplateMiddleToken || tt == js.TemplateEndToken:
+			value, err := opts.substitute(expr.Bytes())
+			if err != nil {
+				return "", n, nil, err
+			}
+			sb.WriteString(value)
+			expr.Reset()
+
+			if tt == js.TemplateMiddleToken {
+				// token is "}text${"
+				sb.WriteString(templateQuoteReplacer.Replace(string(token[1 : len(token)-2])))
+				continue
+			}
+
+			// token is "}text`"
+			sb.WriteString(templateQuoteReplacer.Replace(string(token[1 : len(token)-1])))
+			return sb.String(), n, nil, nil
+		}
+
+		expr.Write(token)
+	}
+}
+
+// isValueEnd returns whether a token of type tt can be the end of a value, which means that
+// an operator after it is not a sign
+func isValueEnd(tt js.TokenType) bool {
+	switch tt {
+	case js.CloseBraceToken, js.CloseBracketToken, js.CloseParenToken:
+		return true
+	}
+	return tt != js.ErrorToken && !js.IsPunctuator(tt) && !js.IsOperator(tt)
+}
+
+// nextToken returns the next token from lex that is not ignored and adds the length of all read tokens to readInputBytes
+func nextToken(lex *js.Lexer, readInputBytes *int) (js.TokenType, []byte) {
+	for {
+		tt, text := lex.Next()
+		*readInputBytes += len(text)
+
+		if !isIgnoredToken(tt) {
+			return tt, text
+		}
+	}
+}
+
+// startsCall returns whether the identifier that was read right before b is the start of a function call like Date(0) or Object.freeze({}).
+// If b ends before this is known, it also returns true
+func startsCall(b []byte) bool {
+	var i = skipJSONSpace(b, 0)
+	return i == len(b) || b[i] == '(' || b[i] == '.'
 }
 
 func isIgnoredToken(tt js.TokenType) bool {
 	return tt == js.WhitespaceToken || tt == js.LineTerminatorToken || tt == js.CommentToken || tt == js.CommentLineTerminatorToken
 }
 
+// legacyOctalLength returns the length of the number with a leading zero (e.g. 017) at the start of b.
+// It returns 0 if b doesn't start with such a number or if it might continue after the end of b
+func legacyOctalLength(b []byte) int {
+	if !isLegacyOctal(b) {
+		return 0
+	}
+
+	var n = digitsEnd(b, 2)
+
+	// Numbers that contain 8 or 9 are decimal numbers, which can also have a fraction or exponent, e.g. 08.5 or 09e1
+	if bytes.IndexAny(b[:n], "89") >= 0 {
+		if n < len(b) && b[n] == '.' {
+			n = digitsEnd(b, n+1)
+		}
+		if n < len(b) && (b[n] == 'e' || b[n] == 'E') {
+			var start = n + 1
+			if start < len(b) && (b[start] == '+' || b[start] == '-') {
+				start++
+			}
+			if n = digitsEnd(b, start); n == start {
+				return 0
+			}
+		}
+	}
+
+	// Things like 017n, 01_7 or 017.5 are not valid JavaScript, we leave them to the lexer
+	if n == len(b) || b[n] == '.' || b[n] == '_' || js.IsIdentifierContinue(b[n:]) {
+		return 0
+	}
+
+	return n
+}
+
+// digitsEnd returns the offset of the first byte at or after i in b that is not a decimal digit
+func digitsEnd(b []byte, i int) int {
+	for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// isLegacyOctal returns whether b starts with a zero that is followed by another digit
+func isLegacyOctal(b []byte) bool {
+	return len(b) >= 2 && b[0] == '0' && '0' <= b[1] && b[1] <= '9'
+}
+
 // transformNumber transforms the given number to a decimal number, if possible. Might return
 // invalid JSON data
 func transformNumber(number []byte) []byte {
@@ -488,20 +1271,29 @@ func transformNumber(number []byte) []byte {
 		out = append(out, '-')
 	}
 
-	// Just parse the number. This also deals with leading zeros, all kinds of
-	// number literals (e.g. 1_00 == 100) etc.
-	ui, err := strconv.ParseUint(string(number), 0, 64)
-	if err != nil {
-		// If we have exactly one dot, and it is at the end
-		// e.g. the number "15."" should be interpreted as "15.0"
-		if number[len(number)-1] == '.' && bytes.IndexByte(number, '.') == len(number)-1 {
-			return append(out, append(number, '0')...)
+	// Numeric separators like in 1_000 are removed, strconv only accepts them in integers
+	if bytes.IndexByte(number, '_') >= 0 {
+		number = bytes.ReplaceAll(number, []byte("_"), nil)
+	}
+
+	// Just parse the number. This also deals with leading zeros and all kinds of number literals (e.g. 0x8_7_f == 2175).
+	// Most integers fit into an uint64, larger ones are parsed as big.Int to keep their exact value
+	u, err := strconv.ParseUint(string(number), 0, 64)
+	if err == nil {
+		return strconv.AppendUint(out, u, 10)
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		if i, ok := new(big.Int).SetString(string(number), 0); ok {
+			return i.Append(out, 10)
 		}
+	}
 
-		// this can happen if the number is a float. We just leave it as that, it should be accepted by JSON parsers
-		return append(out, number...)
+	// If we have exactly one dot, and it is at the end
+	// e.g. the number "15."" should be interpreted as "15.0"
+	if number[len(number)-1] == '.' && bytes.IndexByte(number, '.') == len(number)-1 {
+		return append(out, append(number, '0')...)
 	}
 
-	// Now convert to decimal
-	return strconv.AppendUint(out, ui, 10)
+	// this can happen if the number is a float. We just leave it as that, it should be accepted by JSON parsers
+	return append(out, number...)
 }
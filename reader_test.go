@@ -1,16 +1,18 @@
 package jsonextract
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"math/rand"
 	"net/http"
 	"os"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -84,6 +86,145 @@ func TestCallback(t *testing.T) {
 	})
 }
 
+func TestReaderMatches(t *testing.T) {
+	var input = "var a = [1, 2];\nvar b = {\n\tkey: 'ä'\n};"
+
+	var want = []Match{
+		{
+			Data:        []byte(`[1,2]`),
+			StartOffset: 8,
+			EndOffset:   14,
+			Line:        1,
+			Column:      9,
+		},
+		{
+			Data:        []byte(`{"key":"ä"}`),
+			StartOffset: 24,
+			EndOffset:   38,
+			Line:        2,
+			Column:      9,
+		},
+	}
+
+	var got []Match
+	err := ReaderMatches(strings.NewReader(input), func(m Match) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ReaderMatches() returned %d matches, want %d", len(got), len(want))
+	}
+
+	for i, m := range got {
+		w := want[i]
+		if !bytes.Equal(m.Data, w.Data) {
+			t.Errorf("match %d: Data = %s, want %s", i, string(m.Data), string(w.Data))
+		}
+		if m.StartOffset != w.StartOffset || m.EndOffset != w.EndOffset {
+			t.Errorf("match %d: offsets = [%d, %d), want [%d, %d)", i, m.StartOffset, m.EndOffset, w.StartOffset, w.EndOffset)
+		}
+		if m.Line != w.Line || m.Column != w.Column {
+			t.Errorf("match %d: position = %d:%d, want %d:%d", i, m.Line, m.Column, w.Line, w.Column)
+		}
+		if source := input[m.StartOffset:m.EndOffset]; source != string(m.Raw) {
+			t.Errorf("match %d: Raw = %q, but offsets point to %q", i, string(m.Raw), source)
+		}
+	}
+}
+
+// naiveObjects returns the objects Reader should find by trying to read an object from every opening bracket
+func naiveObjects(input []byte) (objects []json.RawMessage) {
+	for i := 0; i < len(input); i++ {
+		if input[i] != openObject && input[i] != openArray {
+			continue
+		}
+
+		out, n, _, err := readJSObject(context.Background(), input[i:], ExtractOptions{}.normalize())
+		if err != nil || !json.Valid(out) {
+			continue
+		}
+
+		objects = append(objects, out)
+		i += n - 1
+	}
+	return
+}
+
+func TestReaderSkipsFailedStarts(t *testing.T) {
+	// Reader skips opening brackets that are known to fail, this must not change the result
+	var parts = []string{"[", "]", "{", "}", "\"", "'", "`", "${", "/", ",", ":", "1", "a", " ", "\n", "-", "+", "NaN", "/*", "*/", "//", "0x1", ".", "*", "\"s\"", "new Date(", "String(", ")", "\\"}
+
+	var rng = rand.New(rand.NewSource(1))
+
+	for i := 0; i < 25000; i++ {
+		var sb strings.Builder
+		for j := rng.Intn(60); j > 0; j-- {
+			sb.WriteString(parts[rng.Intn(len(parts))])
+		}
+		var input = sb.String()
+
+		got, err := readerObjects(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := naiveObjects([]byte(input)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("readerObjects(%q) = %v, want %v", input, convert(got), convert(want))
+		}
+	}
+}
+
+func TestReaderContext(t *testing.T) {
+	t.Run("cancelled before reading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var calls int
+		err := ReaderContext(ctx, strings.NewReader(`{}[]{}`), func(b []byte) error {
+			calls++
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("ReaderContext() returned %v, want context.Canceled", err)
+		}
+		if calls != 0 {
+			t.Errorf("callback was called %d times after context was cancelled", calls)
+		}
+	})
+
+	t.Run("cancelled in callback", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var calls int
+		err := ReaderContext(ctx, strings.NewReader(`{}[]{}`), func(b []byte) error {
+			calls++
+			cancel()
+			return nil
+		})
+		if err != context.Canceled {
+			t.Errorf("ReaderContext() returned %v, want context.Canceled", err)
+		}
+		if calls != 1 {
+			t.Errorf("callback was called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("cancelled while reading an object", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, _, _, err := readJSObject(ctx, []byte(`{"a": [1, 2, 3]}`), ExtractOptions{})
+		if err != context.Canceled {
+			t.Errorf("readJSObject() returned %v, want context.Canceled", err)
+		}
+	})
+}
+
 func readerObjects(reader io.Reader) (objects []json.RawMessage, err error) {
 	return objects, Reader(reader, func(b []byte) error {
 		objects = append(objects, b)
@@ -239,6 +380,36 @@ var testData = []struct {
 			[]byte(`{"n":null}`),
 		},
 	},
+	{
+		`var x = {label: "a" + 'b' + ` + "`c`" + `, url: "https://" + host, list: ["x" + 1]}`,
+		[]json.RawMessage{
+			[]byte(`["x1"]`),
+		},
+	},
+	{
+		`var x = {label: "a" + 'b' + ` + "`c`" + `}`,
+		[]json.RawMessage{
+			[]byte(`{"label":"abc"}`),
+		},
+	},
+	{
+		`var x = {n: Infinity, m: -Infinity, o: [+Infinity]}`,
+		[]json.RawMessage{
+			[]byte(`{"n":null,"m":null,"o":[null]}`),
+		},
+	},
+	{
+		`{Infinity: 1}`,
+		[]json.RawMessage{
+			[]byte(`{"Infinity":1}`),
+		},
+	},
+	{
+		`{Infinity: Infinity, NaN : NaN, undefined:undefined}`,
+		[]json.RawMessage{
+			[]byte(`{"Infinity":null,"NaN":null,"undefined":null}`),
+		},
+	},
 	{
 		// This was found using https://github.com/dvyukov/go-fuzz
 		"[`",
@@ -710,6 +881,18 @@ var testData = []struct {
 			[]byte("{}"),
 		},
 	},
+	{
+		strings.Repeat("[", 100000),
+		nil,
+	},
+	{
+		// The regex must be skipped entirely, the array in it must not be extracted again
+		`{re: /a[1]b/} [2]`,
+		[]json.RawMessage{
+			[]byte(`{"re":"/a[1]b/"}`),
+			[]byte(`[2]`),
+		},
+	},
 	{
 		strings.Repeat("[", 100) + "]",
 		[]json.RawMessage{
@@ -792,106 +975,195 @@ var readerTestData = []struct {
 	},
 }
 
-func TestResettableRuneBuffer(t *testing.T) {
+func TestReadJSObject(t *testing.T) {
 	for _, tt := range readerTestData {
 		t.Run(t.Name(), func(t *testing.T) {
-			var r = newResettableBuffer(strings.NewReader(tt.input))
-
-			r.MarkStart()
-
-			err := iotest.TestReader(r, []byte(tt.input))
+			_, n, _, err := readJSObject(context.Background(), []byte(tt.input), ExtractOptions{}.normalize())
 			if err != nil {
-				t.Errorf("Invalid resettableRuneBuffer implementation (initial read): %s", err.Error())
+				// Not all inputs are objects that can be converted, e.g. "{{}}" is not allowed
+				return
 			}
 
-			err = r.ReturnAndSkip(len(tt.input) / 2)
-			if err != nil {
-				panic(err)
-			}
-
-			r.MarkEnd()
-
-			err = iotest.TestReader(r, []byte(tt.input[len(tt.input)/2:]))
-			if err != nil {
-				t.Errorf("Invalid resettableRuneBuffer implementation (after returning): %s", err.Error())
+			if got := tt.input[:n]; got != tt.want {
+				t.Errorf("readJSObject(%q) read %q, want %q", tt.input, got, tt.want)
 			}
 		})
 	}
 
-	t.Run("can reset multiple times", func(t *testing.T) {
-		var input = strings.Repeat("abcde", 50)
-
-		var r = newResettableBuffer(bufio.NewReaderSize(strings.NewReader(input), 32))
+	t.Run("incomplete input", func(t *testing.T) {
+		var input = readerTestData[1].want
 
-		// Throw away 2 bytes, now we should start at "cde"
-		_, err := io.CopyN(io.Discard, r, 2)
-		if err != nil {
-			t.Errorf("unexpected copy fail: %v", err)
+		// Cutting the object off anywhere must not result in an error other than errIncomplete
+		for i := 1; i < len(input); i++ {
+			_, _, _, err := readJSObject(context.Background(), []byte(input[:i]), ExtractOptions{}.normalize())
+			if err != errIncomplete {
+				t.Errorf("readJSObject(%q) returned error %v, want errIncomplete", input[:i], err)
+			}
 		}
+	})
+}
 
-		r.MarkStart()
+func TestObjectReaderResume(t *testing.T) {
+	var inputs = []string{
+		`{a: 1, "b": [1, 2, ], c: 'x' + "y", d: -Infinity, e: -NaN, f: +5, g: 0x1_0n, /* comment */ h: /re[/]g/}`,
+		`[new Date(0), Object.freeze({a: [1,]}), 60 * 60 * 24, ` + "`t${x}`" + `, -3, undefined, 017, 1.]`,
+		`{"nested": {"deep": [[[{}]]], "s": "a\"b"}, "last": [true, false, null]}`,
+		"{a: 1, // line comment\n b: [2] /* block comment */, c: 3}",
+		`{"a": [,], "b": {,}, "c": - +1, "d": [[{}, [1, {"e": [2]}]], 3]}`,
+		`{Infinity    : Infinity    , NaN      :[NaN       ], undefined :  undefined       }`,
+	}
+	var opts = ExtractOptions{Arithmetic: true, Templates: TemplateVerbatim}.normalize()
 
-		n, err := io.CopyN(io.Discard, r, 202)
+	for _, input := range inputs {
+		want, wantN, wantBrackets, err := readJSObject(context.Background(), []byte(input), opts)
 		if err != nil {
-			t.Errorf("unexpected copy fail: %v", err)
-		}
-		if n != 202 {
-			t.Errorf("only read %d bytes even though more are available", n)
+			t.Fatalf("readJSObject(%q) returned unexpected error %v", input, err)
 		}
 
-		// Now we should be able to read "eab"
-		var b2 = make([]byte, 3)
+		// Reading the start of the input first must not change the result
+		for i := 1; i < len(input); i++ {
+			var r objectReader
+			if _, _, _, err := r.read(context.Background(), []byte(input[:i]), opts); err != errIncomplete {
+				t.Fatalf("read(%q) returned %v, want errIncomplete", input[:i], err)
+			}
 
-		n2, err := r.Read(b2)
-		if err != nil {
-			t.Errorf("unexpected read fail: %v", err)
-		}
-		if n2 != len(b2) {
-			t.Errorf("didn't fill the entire buffer b2 even though there should be data available")
-		}
-		if !bytes.Equal(b2, []byte(`eab`)) {
-			t.Errorf("buffer returned wrong data")
+			got, n, brackets, err := r.read(context.Background(), []byte(input), opts)
+			if err != nil {
+				t.Fatalf("continuing after %q returned unexpected error %v", input[:i], err)
+			}
+			if string(got) != string(want) || n != wantN || !reflect.DeepEqual(brackets, wantBrackets) {
+				t.Errorf("continuing after %q returned %s (%d bytes), want %s (%d bytes)", input[:i], got, n, want, wantN)
+			}
 		}
+	}
+}
 
-		// Reset the buffer. We should now be at "cde" again, but we skip one rune, which
-		// means that the start should now be "de"
-		err = r.ReturnAndSkipOne()
-		if err != nil {
-			t.Errorf("unexpected reset fail: %v", err)
+func TestReadJSObjectStopsEarly(t *testing.T) {
+	// Reading must stop at the first token that can't be part of an object instead of reading until the brackets are closed
+	var rest = strings.Repeat(` foo, "bar": [1, {}], `, 1000) + "}]"
+
+	for _, input := range []string{
+		`{ hello world`,
+		`[{"a": 1}` + "\n" + `{`,
+		`[1 2`,
+		`{"a": 1 "b"`,
+		`{"a" [`,
+		`[1, ,`,
+		`[-true`,
+		`[function`,
+		`[)`,
+		`{a: [1}`,
+	} {
+		_, n, _, err := readJSObject(context.Background(), []byte(input+rest), ExtractOptions{}.normalize())
+		if err == nil || n > len(input) {
+			t.Errorf("readJSObject(%q...) read %d bytes and returned %v, want an error after at most %d bytes", input, n, err, len(input))
 		}
+	}
+}
 
-		var b3 = make([]byte, 64)
-		n3, err := r.Read(b3)
-		if err != nil {
-			t.Errorf("unexpected read fail: %v", err)
-		}
-		if n3 != len(b3) {
-			t.Errorf("didn't fill the entire buffer b3 even though there should be data available")
+func TestReadJSObjectGrammar(t *testing.T) {
+	runConversionTests(t, []conversionTest{
+		{arg: `[,]`, want: `[]`},
+		{arg: `{,}`, want: `{}`},
+		{arg: `[- +1, +1]`, want: `[-1,1]`},
+		{arg: `{0x1 + "k": 0}`, want: `{"1k":0}`},
+		{arg: `[1,,]`, wantErr: true},
+		{arg: `[,1]`, wantErr: true},
+		{arg: `{a b}`, wantErr: true},
+		{arg: `{"a" 1}`, wantErr: true},
+		{arg: `{a: 1,, b: 2}`, wantErr: true},
+		{arg: `[(1)]`, wantErr: true},
+		{arg: `[true false]`, wantErr: true},
+		{arg: `[{]`, wantErr: true},
+	})
+}
+
+func TestWindow(t *testing.T) {
+	var input = strings.Repeat("abcde", 5*minWindowSize)
+
+	var w = newWindow(iotest.OneByteReader(strings.NewReader(input)))
+
+	w.Fill(3)
+	if !bytes.HasPrefix(w.Bytes(), []byte("abc")) {
+		t.Fatalf("window should start with the input, but got %q", string(w.Bytes()))
+	}
+
+	// Skip a bit, then request more than the initial size
+	w.Advance(2)
+	w.Fill(3 * minWindowSize)
+
+	if len(w.Bytes()) != 3*minWindowSize {
+		t.Errorf("window has %d bytes available, want %d", len(w.Bytes()), 3*minWindowSize)
+	}
+	if !bytes.HasPrefix(w.Bytes(), []byte("cdeab")) {
+		t.Errorf("window should continue after skipped data, but got %q", string(w.Bytes()[:5]))
+	}
+	if w.Complete() {
+		t.Errorf("window should not be complete yet")
+	}
+
+	// Now read everything
+	var read = 2
+	for {
+		w.Fill(minWindowSize)
+
+		var data = w.Bytes()
+		if len(data) == 0 {
+			break
 		}
 
-		if !bytes.HasPrefix(b3, []byte("deabcde")) {
-			t.Errorf("b3 read returned wrong data")
+		if string(data) != input[read:read+len(data)] {
+			t.Fatalf("window returned wrong data at offset %d", read)
 		}
 
-		// Reset again. We reset to "de...", but now we skip a few more bytes in the process
-		err = r.ReturnAndSkip(32)
-		if err != nil {
-			t.Errorf("unexpected second reset fail: %v", err)
+		read += len(data)
+		w.Advance(len(data))
+	}
+
+	if read != len(input) {
+		t.Errorf("window only returned %d of %d bytes", read, len(input))
+	}
+	if !w.Complete() || w.Err() != nil {
+		t.Errorf("window should be complete without error, but got %v", w.Err())
+	}
+	if cap(w.Bytes()) <= len(w.Bytes()) {
+		t.Errorf("window has no spare capacity after its data, the lexer would have to copy it")
+	}
+	if cap(w.buf) > 4*minWindowSize {
+		t.Errorf("window grew to %d bytes, which is more than needed", cap(w.buf))
+	}
+}
+
+func TestWindowLookback(t *testing.T) {
+	var input = strings.Repeat("abcde", 5*minWindowSize)
+
+	var w = newWindow(iotest.OneByteReader(strings.NewReader(input)))
+	w.lookback = 7
+
+	for read := 0; ; {
+		w.Fill(minWindowSize)
+
+		var data = w.Bytes()
+		if len(data) == 0 {
+			break
 		}
 
-		var b4 = make([]byte, 5)
-		n4, err := r.Read(b4)
-		if err != nil {
-			t.Errorf("unexpected read fail: %v", err)
+		var wantStart = read - w.lookback
+		if wantStart < 0 {
+			wantStart = 0
 		}
-		if n4 != len(b4) {
-			t.Errorf("didn't fill the entire buffer b4 even though there should be data available")
+		if before := w.Before(); string(before) != input[wantStart:read] {
+			t.Fatalf("window returned %q before offset %d, want %q", string(before), read, input[wantStart:read])
 		}
 
-		if !bytes.Equal(b4, []byte("abcde")) {
-			t.Errorf("b4 read returned wrong data")
+		// Advance by an odd number to make sure lookback data is moved around
+		var n = 3*minWindowSize/4 + 1
+		if n > len(data) {
+			n = len(data)
 		}
-	})
+		read += n
+		w.Advance(n)
+	}
 }
 
 // Test to check if the example program still works
@@ -943,11 +1215,21 @@ func Test_transformNumber(t *testing.T) {
 			"100",
 		},
 		{
-			// Note: while this works with this function, the lexer doesn't recognize these numbers
-			// and returns an error for them
 			"1_00",
 			"100",
 		},
+		{
+			"1_000.5_5",
+			"1000.55",
+		},
+		{
+			"1e1_0",
+			"1e10",
+		},
+		{
+			"0x8_7_f",
+			"2175",
+		},
 		{
 			"0x0000000000000000000045",
 			"69",
@@ -957,7 +1239,7 @@ func Test_transformNumber(t *testing.T) {
 			"0",
 		},
 		{
-			// Cannot be parsed, but will still be returned
+			// Too large for uint64, but the exact value is kept
 			"11823701928340192387409128357019283740912837409128374901263478912634978127356981273489127346",
 			"11823701928340192387409128357019283740912837409128374901263478912634978127356981273489127346",
 		},
@@ -1036,3 +1318,358 @@ func Test_transformNumber(t *testing.T) {
 		})
 	}
 }
+
+// conversionTest is a test case for readJSObject. If wantErr is set, the output is not checked
+type conversionTest struct {
+	arg     string
+	opts    ExtractOptions
+	want    string
+	wantErr bool
+}
+
+func runConversionTests(t *testing.T, tests []conversionTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, _, _, err := readJSObject(context.Background(), []byte(tt.arg), tt.opts.normalize())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readJSObject(%q) error = %v, wantErr %v", tt.arg, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("readJSObject(%q) = %s, want %s", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadJSObjectBigInt(t *testing.T) {
+	runConversionTests(t, []conversionTest{
+		{arg: `[5n, 0x5n, 0o17n, 0b101n]`, want: `[5,5,15,5]`},
+		{arg: `[-123n, +123n]`, want: `[-123,123]`},
+		{arg: `[0x1_0000_0000_0000_0000n]`, want: `[18446744073709551616]`},
+		{arg: `[0x` + strings.Repeat("f", 32) + `]`, want: `[340282366920938463463374607431768211455]`},
+		{arg: `[0b1` + strings.Repeat("0", 70) + `]`, want: `[1180591620717411303424]`},
+		{arg: `[0o1` + strings.Repeat("0", 30) + `n]`, want: `[1237940039285380274899124224]`},
+		{arg: `[123456789012345678901234567890n]`, want: `[123456789012345678901234567890]`},
+		{arg: `[18446744073709551615, 18446744073709551616, -18446744073709551616]`, want: `[18446744073709551615,18446744073709551616,-18446744073709551616]`},
+		{arg: `[5n, 0x10n, -3n, 7]`, opts: ExtractOptions{BigIntAsString: true}, want: `["5","16","-3",7]`},
+	})
+}
+
+func TestReadJSObjectNumbers(t *testing.T) {
+	runConversionTests(t, []conversionTest{
+		{arg: `[1_000]`, want: `[1000]`},
+		{arg: `[0x8_7_f, 0b1_0, 0o1_7]`, want: `[2175,2,15]`},
+		{arg: `[1_000.000_1, 1e1_0]`, want: `[1000.0001,1e10]`},
+		{arg: `[017]`, want: `[15]`},
+		{arg: `[-017, +017]`, want: `[-15,15]`},
+		{arg: `{"a": 0777}`, want: `{"a":511}`},
+		{arg: `[08, 019, 00]`, want: `[8,19,0]`},
+		{arg: `[08.5, -09.1, 0019.25]`, want: `[8.5,-9.1,19.25]`},
+		{arg: `[08., 09e1, 08.5E-1, 08.e1]`, want: `[8.0,9e1,8.5E-1,8.0e1]`},
+		{arg: `[08.5]`, opts: ExtractOptions{LeadingZero: LeadingZeroDecimal}, want: `[8.5]`},
+		{arg: `[08.5]`, opts: ExtractOptions{LeadingZero: LeadingZeroDisallow}, wantErr: true},
+		{arg: `[08.5]`, opts: ExtractOptions{Arithmetic: true}, want: `[8.5]`},
+		{arg: `[08.5 * 2]`, opts: ExtractOptions{Arithmetic: true}, want: `[17]`},
+		{arg: `[07.5]`, wantErr: true},
+		{arg: `[08e]`, wantErr: true},
+		{arg: `[08.5n]`, wantErr: true},
+		{arg: `[017]`, opts: ExtractOptions{LeadingZero: LeadingZeroDecimal}, want: `[17]`},
+		{arg: `[-0017, 00]`, opts: ExtractOptions{LeadingZero: LeadingZeroDecimal}, want: `[-17,0]`},
+		{arg: `[017]`, opts: ExtractOptions{LeadingZero: LeadingZeroDisallow}, wantErr: true},
+		{arg: `[017]`, opts: ExtractOptions{DisallowNumberFormats: true}, wantErr: true},
+		{arg: `[1_000]`, opts: ExtractOptions{DisallowNumberFormats: true}, wantErr: true},
+		{arg: `[017]`, opts: ExtractOptions{Strict: true}, wantErr: true},
+		{arg: `[017n]`, wantErr: true},
+		{arg: `[01_7]`, wantErr: true},
+		{arg: `[0_1]`, wantErr: true},
+		{arg: `[0` + strings.Repeat("7", 30) + `]`, want: `[1237940039285380274899124223]`},
+	})
+}
+
+// repeatReader returns data over and over again until n bytes were read
+type repeatReader struct {
+	data []byte
+	off  int
+	n    int64
+}
+
+func (r *repeatReader) Read(p []byte) (n int, err error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+
+	for n < len(p) {
+		c := copy(p[n:], r.data[r.off:])
+		r.off = (r.off + c) % len(r.data)
+		n += c
+	}
+
+	r.n -= int64(n)
+	return n, nil
+}
+
+// heapInUse returns the amount of heap memory that is currently in use
+func heapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse
+}
+
+// BenchmarkReaderStreaming shows that the memory used while reading stays the same, no matter the size of the input
+func BenchmarkReaderStreaming(b *testing.B) {
+	var data = []byte(`<script>var x = {key: 'value', "arr": [1, 2, 0x3, {a: undefined}]};</script>` + "\n")
+
+	for _, size := range []int64{1 << 20, 16 << 20, 128 << 20} {
+		b.Run(fmt.Sprintf("%dMiB", size>>20), func(b *testing.B) {
+			b.SetBytes(size)
+			b.ReportAllocs()
+
+			runtime.GC()
+			var (
+				before  = heapInUse()
+				maxHeap = before
+			)
+
+			for i := 0; i < b.N; i++ {
+				var calls int
+				err := Reader(&repeatReader{data: data, n: size}, func(b []byte) error {
+					calls++
+					if calls%10000 == 0 {
+						if h := heapInUse(); h > maxHeap {
+							maxHeap = h
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			b.ReportMetric(float64(maxHeap-before)/(1<<10), "KiB-heap-growth")
+		})
+	}
+}
+
+// heapSamplingReader records the largest heap size seen while r is read
+type heapSamplingReader struct {
+	r       io.Reader
+	read    int
+	maxHeap uint64
+}
+
+func (h *heapSamplingReader) Read(p []byte) (n int, err error) {
+	n, err = h.r.Read(p)
+
+	// Sampling on every call would take longer than reading
+	if h.read/(1<<20) != (h.read+n)/(1<<20) {
+		if heap := heapInUse(); heap > h.maxHeap {
+			h.maxHeap = heap
+		}
+	}
+	h.read += n
+
+	return
+}
+
+// BenchmarkReaderStrayBracket shows that an opening bracket that doesn't start an object, e.g. in a log file,
+// doesn't make the reader keep the rest of the input in memory
+func BenchmarkReaderStrayBracket(b *testing.B) {
+	var inputs = []struct {
+		name       string
+		start      string
+		data       []byte
+		opts       ExtractOptions
+		wantCalled bool
+	}{
+		{"text", "{ ", []byte("hello world foo bar, "), ExtractOptions{}, false},
+		{"lines", "[ ", []byte(`{"level": "info", "msg": "started"}` + "\n"), ExtractOptions{}, true},
+		// Without the limit, this would be read as one huge array
+		{"list", "[ ", []byte(`{"level": "info", "msg": "started"}, `), ExtractOptions{MaxObjectSize: 1 << 20}, true},
+	}
+
+	for _, input := range inputs {
+		for _, size := range []int64{16 << 20, 128 << 20} {
+			b.Run(fmt.Sprintf("%s-%dMiB", input.name, size>>20), func(b *testing.B) {
+				b.SetBytes(size)
+				b.ReportAllocs()
+
+				runtime.GC()
+				var (
+					before  = heapInUse()
+					maxHeap = before
+				)
+
+				for i := 0; i < b.N; i++ {
+					var r = &heapSamplingReader{
+						r: io.MultiReader(strings.NewReader(input.start), &repeatReader{data: input.data, n: size}),
+					}
+
+					var called bool
+					err := ReaderWithOptions(context.Background(), r, input.opts, func(m Match) error {
+						called = true
+						return nil
+					})
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+					if called != input.wantCalled {
+						b.Fatalf("callback called: %v, want %v", called, input.wantCalled)
+					}
+
+					if r.maxHeap > maxHeap {
+						maxHeap = r.maxHeap
+					}
+				}
+
+				b.ReportMetric(float64(maxHeap-before)/(1<<10), "KiB-heap-growth")
+			})
+		}
+	}
+}
+
+// BenchmarkReaderLargeObject shows that the memory used while reading is proportional to the size of the largest object
+func BenchmarkReaderLargeObject(b *testing.B) {
+	for _, size := range []int64{1 << 20, 16 << 20} {
+		b.Run(fmt.Sprintf("%dMiB", size>>20), func(b *testing.B) {
+			b.SetBytes(size)
+			b.ReportAllocs()
+
+			var element = []byte(`{a: 1, b: "value"}, `)
+
+			for i := 0; i < b.N; i++ {
+				var r = io.MultiReader(
+					strings.NewReader("["),
+					&repeatReader{data: element, n: size - size%int64(len(element))},
+					strings.NewReader("]"),
+				)
+
+				var calls int
+				err := Reader(r, func(b []byte) error {
+					calls++
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				if calls != 1 {
+					b.Fatalf("expected exactly one object, but got %d", calls)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkReaderLargeObjectThenSmall reads one large object followed by many small ones.
+// The window grows to hold the large object, which must not make reading the small objects slower
+func BenchmarkReaderLargeObjectThenSmall(b *testing.B) {
+	for _, size := range []int64{1 << 20, 4 << 20} {
+		b.Run(fmt.Sprintf("%dMiB", size>>20), func(b *testing.B) {
+			b.SetBytes(2 * size)
+			b.ReportAllocs()
+
+			var (
+				element = []byte(`{a: 1, b: "value"}, `)
+				small   = []byte(`[0] `)
+			)
+
+			for i := 0; i < b.N; i++ {
+				var r = io.MultiReader(
+					strings.NewReader("["),
+					&repeatReader{data: element, n: size - size%int64(len(element))},
+					strings.NewReader("]"),
+					&repeatReader{data: small, n: size},
+				)
+
+				var calls int
+				err := Reader(r, func(b []byte) error {
+					calls++
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+				if want := 1 + int(size)/len(small); calls != want {
+					b.Fatalf("expected %d objects, but got %d", want, calls)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkReaderAdversarial runs the reader on input that contains many opening brackets where no object can be extracted.
+// The time needed should grow linearly with the input size
+func BenchmarkReaderAdversarial(b *testing.B) {
+	var inputs = map[string]func(n int) string{
+		"unclosed-arrays": func(n int) string {
+			return strings.Repeat("[", n)
+		},
+		"unclosed-objects": func(n int) string {
+			return strings.Repeat("{", n)
+		},
+		"invalid-center": func(n int) string {
+			return strings.Repeat("[", n) + "{" + strings.Repeat("]", n)
+		},
+		"error-center": func(n int) string {
+			return strings.Repeat("[", n) + "1+1" + strings.Repeat("]", n)
+		},
+		// Each bracket is followed by a token that continues until the end of the input
+		"unclosed-comments": func(n int) string {
+			return strings.Repeat("[/*", n)
+		},
+		"unclosed-line-comments": func(n int) string {
+			return strings.Repeat("[//", n)
+		},
+		"unclosed-strings": func(n int) string {
+			return strings.Repeat(`["`, n)
+		},
+		"unclosed-regex": func(n int) string {
+			return strings.Repeat("[/", n)
+		},
+		"unclosed-templates": func(n int) string {
+			return strings.Repeat("[`${", n)
+		},
+	}
+
+	for _, name := range []string{"unclosed-arrays", "unclosed-objects", "invalid-center", "error-center",
+		"unclosed-comments", "unclosed-line-comments", "unclosed-strings", "unclosed-regex", "unclosed-templates"} {
+		for _, n := range []int{1000, 10000, 100000} {
+			var input = inputs[name](n)
+
+			b.Run(fmt.Sprintf("%s-%d", name, n), func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+
+				for i := 0; i < b.N; i++ {
+					err := Reader(strings.NewReader(input), func(b []byte) error {
+						return nil
+					})
+					if err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTransformNumber(b *testing.B) {
+	for _, number := range []string{"1617181920000", "-21", "0x8_7_f", "1.5e3", "123456789012345678901234567890"} {
+		b.Run(number, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var input = []byte(number)
+			for i := 0; i < b.N; i++ {
+				transformNumber(input)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package jsonextract
+
+import "errors"
+
+// errUnterminated is returned from readObject if the object contains a token that is known to continue until the end of the input
+var errUnterminated = errors.New("object contains a token that doesn't end before the input")
+
+// unterminatedTokens remembers tokens that were found to continue until the end of the input.
+// Tokens of the same kind that start after them can often not end either, e.g. a block comment
+// can't end if there is no "*/" after an earlier one. Without it, input like many opening brackets
+// followed by the start of a comment would be read until its end for every bracket
+type unterminatedTokens struct {
+	// from maps the start of a token ("/*", "//", `"`, "'", "`" and "/" for regex patterns) to
+	// the offset of the first such token that continued until the end of the input
+	from map[string]int64
+
+	// templates contains the offsets of template literals with substitutions that never ended.
+	// The same template can also be read when starting within an earlier one, so it can't end either
+	templates map[int64]bool
+}
+
+func newUnterminatedTokens() *unterminatedTokens {
+	return &unterminatedTokens{
+		from:      make(map[string]int64),
+		templates: make(map[int64]bool),
+	}
+}
+
+// tokenPrefixLength returns the length of the start of the comment, string or template at the start of b
+// that is used as key in unterminatedTokens.from, or 0 if b doesn't start with such a token
+func tokenPrefixLength(b []byte) int {
+	switch {
+	case len(b) == 0:
+		return 0
+	case b[0] == '"' || b[0] == '\'' || b[0] == '`':
+		return 1
+	case b[0] == '/' && len(b) > 1 && (b[1] == '*' || b[1] == '/'):
+		return 2
+	}
+	return 0
+}
+
+// token returns whether the comment, string or template at the start of b, which starts at offset in the input,
+// continues until the end of the input
+func (u *unterminatedTokens) token(b []byte, offset int64) bool {
+	if u == nil || len(u.from) == 0 {
+		return false
+	}
+
+	n := tokenPrefixLength(b)
+	if n == 0 {
+		return false
+	}
+
+	from, ok := u.from[string(b[:n])]
+	return ok && from <= offset
+}
+
+// regex returns whether the regex pattern at the start of b, which starts at offset in the input,
+// continues until the end of the input
+func (u *unterminatedTokens) regex(b []byte, offset int64) bool {
+	if u == nil {
+		return false
+	}
+
+	from, ok := u.from["/"]
+	if !ok || offset < from {
+		return false
+	}
+
+	// The earlier pattern contains this one. Where the earlier one was still inside a character class,
+	// this one might end with a '/'. Once one of them enters or leaves a class, they are in the same state
+	// and this one can't end either
+	for i := 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '/':
+			return false
+		case '[', ']':
+			return true
+		}
+	}
+	return true
+}
+
+// template returns whether the template literal with substitutions at offset continues until the end of the input
+func (u *unterminatedTokens) template(offset int64) bool {
+	return u != nil && u.templates[offset]
+}
+
+// cutToken describes a token that was still being read when the end of the input was reached
+type cutToken struct {
+	// start is the offset of the token in the input given to the lexer, or -1 if no token was cut off
+	start int
+
+	// regex is set if the token is a regex pattern, template if it is a template literal with substitutions
+	regex, template bool
+
+	// nested contains the offsets of templates within a template literal that didn't end, relative to start
+	nested []int
+}
+
+// add records that the token cut describes continues until the end of the input. b is the input given to the lexer,
+// it starts at offset in the input
+func (u *unterminatedTokens) add(b []byte, offset int64, cut cutToken) {
+	if u == nil || cut.start < 0 {
+		return
+	}
+
+	var (
+		start = offset + int64(cut.start)
+		key   string
+	)
+	switch {
+	case cut.regex:
+		key = "/"
+	case cut.template:
+		u.templates[start] = true
+		for _, off := range cut.nested {
+			u.templates[start+int64(off)] = true
+		}
+		return
+	default:
+		n := tokenPrefixLength(b[cut.start:])
+		if n == 0 {
+			return
+		}
+		key = string(b[cut.start : cut.start+n])
+	}
+
+	if from, ok := u.from[key]; !ok || start < from {
+		u.from[key] = start
+	}
+}
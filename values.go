@@ -0,0 +1,130 @@
+package jsonextract
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+)
+
+// ValuePredicate decides whether the value of a key is accepted by ObjectOption.Values. value is the value as JSON, e.g. "video" including the quotes
+type ValuePredicate func(value json.RawMessage) bool
+
+// JSONType is the type of a JSON value, see IsType
+type JSONType int
+
+const (
+	// TypeNull is the type of null
+	TypeNull JSONType = iota
+
+	// TypeBool is the type of true and false
+	TypeBool
+
+	// TypeNumber is the type of numbers
+	TypeNumber
+
+	// TypeString is the type of strings
+	TypeString
+
+	// TypeArray is the type of arrays
+	TypeArray
+
+	// TypeObject is the type of objects
+	TypeObject
+)
+
+// typeOf returns the type of the JSON value b, which must be valid JSON
+func typeOf(b []byte) JSONType {
+	switch b[0] {
+	case 'n':
+		return TypeNull
+	case 't', 'f':
+		return TypeBool
+	case '"':
+		return TypeString
+	case '[':
+		return TypeArray
+	case '{':
+		return TypeObject
+	default:
+		return TypeNumber
+	}
+}
+
+// IsType returns a predicate that accepts values of type t
+func IsType(t JSONType) ValuePredicate {
+	return func(value json.RawMessage) bool {
+		return typeOf(value) == t
+	}
+}
+
+// Equals returns a predicate that accepts values that are equal to v, e.g. Equals("video") or Equals(5).
+// Values are compared after decoding them with encoding/json, which means that numbers are compared as float64
+// and that v can also be a map or slice. If v can't be encoded to JSON, no value is accepted
+func Equals(v interface{}) ValuePredicate {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return func(json.RawMessage) bool { return false }
+	}
+
+	var want interface{}
+	if err = json.Unmarshal(encoded, &want); err != nil {
+		return func(json.RawMessage) bool { return false }
+	}
+
+	return func(value json.RawMessage) bool {
+		var got interface{}
+		return json.Unmarshal(value, &got) == nil && reflect.DeepEqual(got, want)
+	}
+}
+
+// MatchesRegexp returns a predicate that accepts strings that match re. Other values are not accepted
+func MatchesRegexp(re *regexp.Regexp) ValuePredicate {
+	return func(value json.RawMessage) bool {
+		s, ok := stringValue(value)
+		return ok && re.MatchString(s)
+	}
+}
+
+// Between returns a predicate that accepts numbers that are at least min and at most max.
+// math.Inf can be used for ranges that are open on one side. Other values are not accepted
+func Between(min, max float64) ValuePredicate {
+	return func(value json.RawMessage) bool {
+		f, ok := numberValue(value)
+		return ok && f >= min && f <= max
+	}
+}
+
+// GreaterThan returns a predicate that accepts numbers that are greater than min. Other values are not accepted
+func GreaterThan(min float64) ValuePredicate {
+	return func(value json.RawMessage) bool {
+		f, ok := numberValue(value)
+		return ok && f > min
+	}
+}
+
+// LessThan returns a predicate that accepts numbers that are less than max. Other values are not accepted
+func LessThan(max float64) ValuePredicate {
+	return func(value json.RawMessage) bool {
+		f, ok := numberValue(value)
+		return ok && f < max
+	}
+}
+
+// stringValue returns the decoded string value, ok is false if value is not a string
+func stringValue(value []byte) (s string, ok bool) {
+	if typeOf(value) != TypeString {
+		return "", false
+	}
+
+	s, err := decodeJSONKey(value)
+	return s, err == nil
+}
+
+// numberValue returns the number value, ok is false if value is not a number
+func numberValue(value []byte) (f float64, ok bool) {
+	if typeOf(value) != TypeNumber {
+		return 0, false
+	}
+
+	return f, json.Unmarshal(value, &f) == nil
+}
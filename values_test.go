@@ -0,0 +1,53 @@
+package jsonextract
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"testing"
+)
+
+func TestValuePredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate ValuePredicate
+		value     string
+		want      bool
+	}{
+		{"equals string", Equals("video"), `"video"`, true},
+		{"equals escaped string", Equals("a\"b"), `"a\"b"`, true},
+		{"equals other string", Equals("video"), `"audio"`, false},
+		{"equals int", Equals(5), `5.0`, true},
+		{"equals number type", Equals(5), `"5"`, false},
+		{"equals nil", Equals(nil), `null`, true},
+		{"equals slice", Equals([]string{"a"}), `["a"]`, true},
+		{"equals map", Equals(map[string]int{"a": 1}), `{"a": 1}`, true},
+		{"equals invalid", Equals(func() {}), `null`, false},
+		{"regexp", MatchesRegexp(regexp.MustCompile(`^UC[\w-]+$`)), `"UCsXVk37bltHxD1rDPwtNM8Q"`, true},
+		{"regexp escaped", MatchesRegexp(regexp.MustCompile(`^a/b$`)), `"a\/b"`, true},
+		{"regexp no match", MatchesRegexp(regexp.MustCompile(`^UC`)), `"PL123"`, false},
+		{"regexp number", MatchesRegexp(regexp.MustCompile(`5`)), `5`, false},
+		{"between", Between(0, 10), `10`, true},
+		{"between open", Between(math.Inf(-1), 0), `-1e10`, true},
+		{"between outside", Between(0, 10), `10.5`, false},
+		{"between string", Between(0, 10), `"5"`, false},
+		{"greater than", GreaterThan(0), `0.01`, true},
+		{"greater than equal", GreaterThan(0), `0`, false},
+		{"less than", LessThan(0), `-3`, true},
+		{"less than null", LessThan(0), `null`, false},
+		{"type null", IsType(TypeNull), `null`, true},
+		{"type bool", IsType(TypeBool), `false`, true},
+		{"type number", IsType(TypeNumber), `-1`, true},
+		{"type string", IsType(TypeString), `"1"`, true},
+		{"type array", IsType(TypeArray), `[]`, true},
+		{"type object", IsType(TypeObject), `{}`, true},
+		{"type mismatch", IsType(TypeObject), `[]`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.predicate(json.RawMessage(tt.value)); got != tt.want {
+				t.Errorf("predicate(%s) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package jsonextract
+
+import (
+	"io"
+)
+
+// minWindowSize is the initial size of the buffer of a window
+const minWindowSize = 4096
+
+// window is a buffer over an io.Reader that only keeps the data that is still needed.
+//
+// Data before pos can be discarded at any time, which means that the memory used only
+// depends on how much data after pos is requested using Fill. Only the last lookback bytes
+// before pos are kept, they are available via Before.
+//
+// The buffer always has at least one byte of spare capacity after the data, which allows the lexer
+// to temporarily put a NULL byte there instead of copying all data (see parse.NewInputBytes).
+type window struct {
+	r io.Reader
+
+	// lookback is the number of processed bytes that are kept
+	lookback int
+
+	// buf contains the data read from r that was not yet discarded
+	buf []byte
+
+	// pos is the index of the next byte in buf that should be processed
+	pos int
+
+	// err is the first error returned by r. Once it is set, no more data will be read
+	err error
+}
+
+func newWindow(r io.Reader) *window {
+	return &window{
+		r:   r,
+		buf: make([]byte, 0, minWindowSize),
+	}
+}
+
+// Bytes returns all buffered data that was not yet processed
+func (w *window) Bytes() []byte {
+	return w.buf[w.pos:]
+}
+
+// Before returns up to lookback bytes that were processed last
+func (w *window) Before() []byte {
+	if w.pos > w.lookback {
+		return w.buf[w.pos-w.lookback : w.pos]
+	}
+	return w.buf[:w.pos]
+}
+
+// Advance marks the next n bytes as processed
+func (w *window) Advance(n int) {
+	w.pos += n
+}
+
+// Complete returns whether Bytes contains all remaining data of the input
+func (w *window) Complete() bool {
+	return w.err != nil
+}
+
+// Fill tries to make sure that at least n bytes are available via Bytes, which might not
+// be possible if the input ends before that
+func (w *window) Fill(n int) {
+	if len(w.buf)-w.pos >= n || w.err != nil {
+		return
+	}
+
+	// Throw away processed data that is not needed for lookback
+	if discard := w.pos - w.lookback; discard > 0 {
+		w.buf = w.buf[:copy(w.buf, w.buf[discard:])]
+		w.pos -= discard
+	}
+
+	// One more byte is needed for the spare capacity
+	if need := w.pos + n + 1; need > cap(w.buf) {
+		var newCap = 2 * cap(w.buf)
+		if newCap < need {
+			newCap = need
+		}
+
+		var newBuf = make([]byte, len(w.buf), newCap)
+		copy(newBuf, w.buf)
+		w.buf = newBuf
+	}
+
+	for len(w.buf)-w.pos < n && w.err == nil {
+		var read int
+		read, w.err = w.r.Read(w.buf[len(w.buf) : cap(w.buf)-1])
+
+		w.buf = w.buf[:len(w.buf)+read]
+	}
+}
+
+// Err returns the error that stopped reading from the input, but only after all data was processed.
+// It returns nil if the input ended normally
+func (w *window) Err() error {
+	if w.pos < len(w.buf) || w.err == io.EOF {
+		return nil
+	}
+	return w.err
+}